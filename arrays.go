@@ -0,0 +1,171 @@
+package jserial
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// primitiveSizes maps the type codes of primitive array elements to their size in bytes.
+var primitiveSizes = map[byte]int{
+	'B': 1,
+	'C': 2,
+	'D': 8,
+	'F': 4,
+	'I': 4,
+	'J': 8,
+	'S': 2,
+	'Z': 1,
+}
+
+// arrayChunkSize is the number of bytes of a primitive array decoded at once, a multiple of every element size.
+const arrayChunkSize = 64 << 10
+
+// primitiveArray reads an array of size primitive elements of type tc taking width bytes each. Arrays are decoded into
+// []byte, []uint16 (char, as UTF-16 code units), []float64, []float32, []int32, []int64, []int16 and []bool.
+func (sop *SerializedObjectParser) primitiveArray(tc byte, width, size int) (arr interface{}, err error) {
+	if sop.indexing {
+		if err = sop.skip(int64(size) * int64(width)); err != nil {
+			err = errors.Wrap(err, "error reading primitive array")
+		}
+
+		return
+	}
+
+	// byte arrays share memory with the input of a zero copy parser
+	if tc == 'B' && sop.zeroCopy {
+		var b []byte
+
+		if b, err = sop.rd.take(size); err != nil {
+			sop.path[len(sop.path)-1].index = len(b)
+
+			if sop.partial(err) {
+				arr = &PartialArray{Elements: b, Length: size}
+			}
+
+			err = errors.Wrap(err, "error reading primitive array")
+
+			return
+		}
+
+		return b, nil
+	}
+
+	chunkSize := size * width
+	if chunkSize > arrayChunkSize {
+		chunkSize = arrayChunkSize
+	}
+
+	// the length is not trusted, the values grow as the elements are read
+	values := makePrimitives(tc, chunkSize/width)
+	chunk := make([]byte, chunkSize)
+
+	for i := 0; i < size; {
+		if err = sop.checkContext(); err != nil {
+			return
+		}
+
+		n := len(chunk) / width
+		if n > size-i {
+			n = size - i
+		}
+
+		var read int
+
+		read, err = io.ReadFull(sop.rd, chunk[:n*width])
+		values = appendPrimitives(values, chunk[:read-read%width])
+		i += read / width
+
+		if err != nil {
+			sop.path[len(sop.path)-1].index = i
+
+			if sop.partial(err) {
+				arr = &PartialArray{Elements: values, Length: size}
+			}
+
+			err = errors.Wrap(err, "error reading primitive array")
+
+			return
+		}
+	}
+
+	arr = values
+
+	return
+}
+
+// makePrimitives returns an empty slice for elements of type tc with room for n elements.
+func makePrimitives(tc byte, n int) interface{} {
+	switch tc {
+	case 'B':
+		return make([]byte, 0, n)
+	case 'C':
+		return make([]uint16, 0, n)
+	case 'D':
+		return make([]float64, 0, n)
+	case 'F':
+		return make([]float32, 0, n)
+	case 'I':
+		return make([]int32, 0, n)
+	case 'J':
+		return make([]int64, 0, n)
+	case 'S':
+		return make([]int16, 0, n)
+	default:
+		return make([]bool, 0, n)
+	}
+}
+
+// appendPrimitives decodes the big endian elements in b and appends them to values.
+func appendPrimitives(values interface{}, b []byte) interface{} {
+	switch v := values.(type) {
+	case []byte:
+		return append(v, b...)
+	case []uint16:
+		for j := 0; j < len(b); j += 2 {
+			v = append(v, binary.BigEndian.Uint16(b[j:]))
+		}
+
+		return v
+	case []float64:
+		for j := 0; j < len(b); j += 8 {
+			v = append(v, math.Float64frombits(binary.BigEndian.Uint64(b[j:])))
+		}
+
+		return v
+	case []float32:
+		for j := 0; j < len(b); j += 4 {
+			v = append(v, math.Float32frombits(binary.BigEndian.Uint32(b[j:])))
+		}
+
+		return v
+	case []int32:
+		for j := 0; j < len(b); j += 4 {
+			v = append(v, int32(binary.BigEndian.Uint32(b[j:])))
+		}
+
+		return v
+	case []int64:
+		for j := 0; j < len(b); j += 8 {
+			v = append(v, int64(binary.BigEndian.Uint64(b[j:])))
+		}
+
+		return v
+	case []int16:
+		for j := 0; j < len(b); j += 2 {
+			v = append(v, int16(binary.BigEndian.Uint16(b[j:])))
+		}
+
+		return v
+	case []bool:
+		for _, x := range b {
+			v = append(v, x != 0)
+		}
+
+		return v
+	default:
+		return values
+	}
+}
@@ -0,0 +1,150 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// primitiveArrayHex returns a stream holding an array of type elemType with size elements encoded in data.
+func primitiveArrayHex(elemType string, size int, data string) string {
+	return streamMagic + streamVersion + tcArray + tcClassDesc + encodeStr("["+elemType) + serialVer + scSerializable +
+		"0000" + tcEndBlockData + tcNull + fmt.Sprintf("%08x", size) + data
+}
+
+func TestPrimitiveArrays(t *testing.T) {
+	tests := []struct {
+		elemType string
+		size     int
+		data     string
+		expected interface{}
+	}{
+		{"B", 3, "01ff80", []byte{0x01, 0xff, 0x80}},
+		// chars are UTF-16 code units, a lone surrogate is kept
+		{"C", 3, "0061d83dde00", []uint16{0x61, 0xd83d, 0xde00}},
+		{"C", 2, "d83d0061", []uint16{0xd83d, 0x61}},
+		{"D", 1, "3ff8000000000000", []float64{1.5}},
+		{"F", 2, "3fc00000c0000000", []float32{1.5, -2}},
+		{"I", 2, "00000001ffffffff", []int32{1, -1}},
+		{"J", 1, "fffffffffffffffe", []int64{-2}},
+		{"S", 2, "7fff8000", []int16{32767, -32768}},
+		{"Z", 3, "000102", []bool{false, true, true}},
+		{"I", 0, "", []int32{}},
+	}
+
+	for _, test := range tests {
+		content, err := ParseSerializedObject(decodeHex(t, primitiveArrayHex(test.elemType, test.size, test.data)))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %+v", test.elemType, err)
+
+			continue
+		}
+		if !reflect.DeepEqual(content[0], test.expected) {
+			t.Errorf("%s: unexpected array %#v", test.elemType, content[0])
+		}
+	}
+}
+
+func TestByteArrayJSON(t *testing.T) {
+	content, err := ParseSerializedObjectMinimal(decodeHex(t, primitiveArrayHex("B", 3, "666f6f")))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	b, err := json.Marshal(content)
+	if err != nil || string(b) != `["Zm9v"]` {
+		t.Errorf("unexpected JSON %s or error %v", b, err)
+	}
+}
+
+func TestTruncatedPrimitiveArray(t *testing.T) {
+	b := decodeHex(t, primitiveArrayHex("I", 3, "000000010000000200"))
+
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetPartialResults(true))
+	content, err := sop.ParseSerializedObject()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !reflect.DeepEqual(content, []interface{}{&PartialArray{Elements: []int32{1, 2}, Length: 3}}) {
+		t.Errorf("unexpected content %#v", content)
+	}
+}
+
+// largeArrayStream returns a stream holding an array of type elemType with size elements of width bytes.
+func largeArrayStream(b *testing.B, elemType string, size, width int) []byte {
+	prefix, err := hex.DecodeString(primitiveArrayHex(elemType, size, ""))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	data := make([]byte, size*width)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	return append(prefix, data...)
+}
+
+func BenchmarkPrimitiveArray(b *testing.B) {
+	const size = 1 << 20
+
+	for _, elemType := range []string{"B", "I"} {
+		width := primitiveSizes[elemType[0]]
+		stream := largeArrayStream(b, elemType, size, width)
+		handler := primitiveHandlers[elemType]
+
+		b.Run(elemType+"/bulk", func(b *testing.B) {
+			b.SetBytes(int64(len(stream)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseSerializedObject(stream); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		// decoding every element into an interface value as done before
+		b.Run(elemType+"/boxed", func(b *testing.B) {
+			b.SetBytes(int64(len(stream)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				sop := NewSerializedObjectParser(bytes.NewReader(stream[len(stream)-size*width:]))
+				array := make([]interface{}, 0, size)
+
+				for j := 0; j < size; j++ {
+					v, err := handler(sop)
+					if err != nil {
+						b.Fatal(err)
+					}
+
+					array = append(array, v)
+				}
+			}
+		})
+	}
+}
+
+func TestPrimitiveArrayLengthNotTrusted(t *testing.T) {
+	// a truncated array of 1<<28 longs only allocates room for the elements read
+	b := decodeHex(t, primitiveArrayHex("J", 1<<28, "0000000000000001"))
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetMaxArrayLength(1<<28), SetMaxAllocBytes(0))
+
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+	_, err := sop.ParseSerializedObject()
+	runtime.ReadMemStats(&after)
+
+	if err == nil {
+		t.Error("expected error for truncated array")
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("allocated %d bytes", allocated)
+	}
+}
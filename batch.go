@@ -0,0 +1,193 @@
+package jserial
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BatchResult is the outcome of decoding one item of a batch.
+type BatchResult struct {
+	// Index is the position of the item in the input.
+	Index   int
+	Content []interface{}
+	Err     error
+}
+
+// Minimal returns the minimal representation of the content, like ParseSerializedObjectMinimal.
+func (r *BatchResult) Minimal() []interface{} {
+	if r.Content == nil {
+		return nil
+	}
+
+	return jsonFriendlyArray(r.Content)
+}
+
+// BatchDecoder decodes many independent serialized streams in parallel.
+type BatchDecoder struct {
+	workers int
+	options []Option
+}
+
+// batchJob is an item of a batch to be decoded by a worker.
+type batchJob struct {
+	index int
+	buf   []byte
+}
+
+// NewBatchDecoder returns a BatchDecoder using the given number of workers, runtime.GOMAXPROCS(0) if not positive.
+// Each worker reuses a single parser created with the options, so resource limits apply to every item.
+func NewBatchDecoder(workers int, options ...Option) *BatchDecoder {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	return &BatchDecoder{workers: workers, options: options}
+}
+
+// Decode decodes the items received from the channel until it is closed and sends the results in input order. The
+// returned channel is closed once every item has been decoded. When ctx is done no more items are read, the items
+// being decoded fail with the context's error and results are dropped unless they are received.
+func (d *BatchDecoder) Decode(ctx context.Context, items <-chan []byte) <-chan BatchResult {
+	return d.DecodeFunc(ctx, func() (buf []byte, more bool) {
+		select {
+		case buf, more = <-items:
+		case <-ctx.Done():
+		}
+
+		return
+	})
+}
+
+// DecodeFunc is like Decode but reads the items by calling next until it returns false.
+func (d *BatchDecoder) DecodeFunc(ctx context.Context, next func() ([]byte, bool)) <-chan BatchResult {
+	jobs := make(chan batchJob)
+	done := make(chan BatchResult, d.workers)
+	out := make(chan BatchResult, d.workers)
+
+	// slots bound the number of items read but not yet sent, which includes results waiting for earlier items
+	slots := make(chan struct{}, 2*d.workers)
+
+	go d.dispatch(ctx, next, jobs, slots)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			d.work(ctx, jobs, done)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	go collect(ctx, done, out, slots)
+
+	return out
+}
+
+// DecodeAll decodes items and returns their results in the same order. Items which were not decoded because ctx is
+// done have the context's error as result.
+func (d *BatchDecoder) DecodeAll(ctx context.Context, items [][]byte) []BatchResult {
+	i := 0
+	results := make([]BatchResult, len(items))
+	decoded := make([]bool, len(items))
+
+	for result := range d.DecodeFunc(ctx, func() ([]byte, bool) {
+		if i == len(items) {
+			return nil, false
+		}
+
+		i++
+
+		return items[i-1], true
+	}) {
+		results[result.Index] = result
+		decoded[result.Index] = true
+	}
+
+	for i := range results {
+		if !decoded[i] {
+			results[i] = BatchResult{Index: i, Err: errors.Wrap(ctx.Err(), "item not decoded")}
+		}
+	}
+
+	return results
+}
+
+// dispatch sends the items returned by next to the workers.
+func (d *BatchDecoder) dispatch(ctx context.Context, next func() ([]byte, bool), jobs chan<- batchJob,
+	slots chan<- struct{}) {
+	defer close(jobs)
+
+	for i := 0; ; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		buf, more := next()
+		if !more {
+			return
+		}
+
+		select {
+		case jobs <- batchJob{index: i, buf: buf}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// work decodes jobs with a reused parser.
+func (d *BatchDecoder) work(ctx context.Context, jobs <-chan batchJob, done chan<- BatchResult) {
+	rd := bytes.NewReader(nil)
+	sop := NewSerializedObjectParser(rd, d.options...)
+
+	for job := range jobs {
+		rd.Reset(job.buf)
+		sop.Reset(rd)
+
+		content, err := sop.ParseSerializedObjectContext(ctx)
+		done <- BatchResult{Index: job.index, Content: content, Err: err}
+	}
+}
+
+// collect sends the results to out in input order.
+func collect(ctx context.Context, done <-chan BatchResult, out chan<- BatchResult, slots <-chan struct{}) {
+	defer close(out)
+
+	pending := map[int]BatchResult{}
+	next := 0
+
+	for result := range done {
+		pending[result.Index] = result
+
+		for {
+			result, exists := pending[next]
+			if !exists {
+				break
+			}
+
+			delete(pending, next)
+			next++
+
+			select {
+			case out <- result:
+			case <-ctx.Done():
+			}
+
+			<-slots
+		}
+	}
+}
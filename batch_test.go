@@ -0,0 +1,138 @@
+package jserial
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// batchItems returns every fixture followed by invalid input, several times over.
+func batchItems() (items [][]byte, names []string) {
+	for name := range objs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i := 0; i < 10; i++ {
+		for _, name := range names {
+			items = append(items, objs[name])
+		}
+
+		items = append(items, []byte("invalid"))
+	}
+
+	return
+}
+
+func TestBatchDecoder(t *testing.T) {
+	items, _ := batchItems()
+
+	results := NewBatchDecoder(4).DecodeAll(context.Background(), items)
+	if len(results) != len(items) {
+		t.Fatalf("unexpected number of results %d", len(results))
+	}
+
+	for i, result := range results {
+		expected, expectedErr := ParseSerializedObject(items[i])
+		if result.Index != i || !reflect.DeepEqual(result.Content, expected) || (result.Err == nil) != (expectedErr == nil) {
+			t.Errorf("unexpected result %d: %+v", i, result)
+		}
+	}
+
+	if minimal := results[0].Minimal(); !reflect.DeepEqual(minimal, jsonFriendlyArray(results[0].Content)) {
+		t.Errorf("unexpected minimal content %v", minimal)
+	}
+}
+
+func TestBatchDecoderChannel(t *testing.T) {
+	items, _ := batchItems()
+
+	in := make(chan []byte)
+	go func() {
+		defer close(in)
+
+		for _, item := range items {
+			in <- item
+		}
+	}()
+
+	i := 0
+	for result := range NewBatchDecoder(0).Decode(context.Background(), in) {
+		if result.Index != i {
+			t.Fatalf("unexpected index %d, expected %d", result.Index, i)
+		}
+		i++
+	}
+
+	if i != len(items) {
+		t.Errorf("unexpected number of results %d", i)
+	}
+}
+
+func TestBatchDecoderLimits(t *testing.T) {
+	items := [][]byte{objs["canary"], objs["nestedArr"]}
+
+	results := NewBatchDecoder(2, SetMaxDepth(3)).DecodeAll(context.Background(), items)
+
+	var limitErr *LimitError
+	if results[0].Err != nil || !errors.As(results[1].Err, &limitErr) {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestBatchDecoderCanceled(t *testing.T) {
+	items, _ := batchItems()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i, result := range NewBatchDecoder(4).DecodeAll(ctx, items) {
+		if result.Index != i || !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("unexpected result %d: %+v", i, result)
+		}
+	}
+
+	// reading stops once the context is done
+	ctx, cancel = context.WithCancel(context.Background())
+	read := 0
+	results := NewBatchDecoder(2).DecodeFunc(ctx, func() ([]byte, bool) {
+		read++
+		if read == 10 {
+			cancel()
+		}
+
+		return objs["canary"], true
+	})
+
+	for range results {
+	}
+
+	if read > 10+2*2 {
+		t.Errorf("unexpected number of items read %d", read)
+	}
+}
+
+func BenchmarkBatchDecoder(b *testing.B) {
+	items, _ := batchItems()
+	total := 0
+	for _, item := range items {
+		total += len(item)
+	}
+
+	for _, workers := range []int{1, 4} {
+		d := NewBatchDecoder(workers)
+
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(total))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				d.DecodeAll(context.Background(), items)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package jserial
+
+import (
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// BlockDataReader presents the data written by a class's custom writeObject / writeExternal method as a single
+// logical stream, much like java.io.ObjectInputStream does for the matching readObject / readExternal method.
+// Primitive reads transparently span block data boundaries while ReadObject returns the next object.
+type BlockDataReader struct {
+	fields map[string]interface{}
+	anns   []interface{}
+	next   int
+	block  []byte
+	in     dataInput
+}
+
+// LegacyPostProc is the PostProc signature of earlier versions, receiving the field values written by
+// defaultWriteObject and the annotations of the class.
+type LegacyPostProc func(fields map[string]interface{}, anns []interface{}) (map[string]interface{}, error)
+
+// AdaptLegacyPostProc returns a PostProc calling proc with the reader's DefaultFields and Annotations, so post
+// processors written for the earlier signature can still be registered.
+func AdaptLegacyPostProc(proc LegacyPostProc) PostProc {
+	return func(r *BlockDataReader) (map[string]interface{}, error) {
+		return proc(r.DefaultFields(), r.Annotations())
+	}
+}
+
+// newBlockDataReader returns a BlockDataReader over the given default field values and annotations.
+func newBlockDataReader(fields map[string]interface{}, anns []interface{}) *BlockDataReader {
+	r := &BlockDataReader{
+		fields: fields,
+		anns:   anns,
+	}
+	r.in.readFully = r.ReadFully
+
+	return r
+}
+
+// DefaultFields returns the field values written by defaultWriteObject. The returned map is the object
+// representation and may be modified and returned by a PostProc.
+func (r *BlockDataReader) DefaultFields() map[string]interface{} {
+	return r.fields
+}
+
+// Annotations returns all block data and objects written by the custom serialization method, regardless of how
+// much has already been read.
+func (r *BlockDataReader) Annotations() []interface{} {
+	return r.anns
+}
+
+// ReadFully reads exactly len(p) bytes of block data into p.
+func (r *BlockDataReader) ReadFully(p []byte) error {
+	for len(p) > 0 {
+		if len(r.block) == 0 {
+			if r.next >= len(r.anns) {
+				return io.EOF
+			}
+
+			block, isBlock := r.anns[r.next].([]byte)
+			if !isBlock {
+				return errors.New("object found where block data was expected")
+			}
+
+			r.block = block
+			r.next++
+
+			continue
+		}
+
+		n := copy(p, r.block)
+		p = p[n:]
+		r.block = r.block[n:]
+	}
+
+	return nil
+}
+
+// SkipBytes skips exactly n bytes of block data.
+func (r *BlockDataReader) SkipBytes(n int) error {
+	return r.ReadFully(make([]byte, n))
+}
+
+// ReadBoolean reads a boolean written by DataOutput#writeBoolean.
+func (r *BlockDataReader) ReadBoolean() (bool, error) {
+	return r.in.readBoolean()
+}
+
+// ReadByte reads a byte written by DataOutput#writeByte. Convert the result with int8 to get Java's signed value.
+func (r *BlockDataReader) ReadByte() (byte, error) {
+	return r.in.readByte()
+}
+
+// ReadShort reads a short written by DataOutput#writeShort.
+func (r *BlockDataReader) ReadShort() (int16, error) {
+	x, err := r.in.readUnsignedShort()
+
+	return int16(x), err
+}
+
+// ReadUnsignedShort reads an unsigned short written by DataOutput#writeShort.
+func (r *BlockDataReader) ReadUnsignedShort() (uint16, error) {
+	return r.in.readUnsignedShort()
+}
+
+// ReadChar reads a char written by DataOutput#writeChar.
+func (r *BlockDataReader) ReadChar() (rune, error) {
+	x, err := r.in.readUnsignedShort()
+
+	return rune(x), err
+}
+
+// ReadInt reads an int written by DataOutput#writeInt.
+func (r *BlockDataReader) ReadInt() (int32, error) {
+	return r.in.readInt()
+}
+
+// ReadLong reads a long written by DataOutput#writeLong.
+func (r *BlockDataReader) ReadLong() (int64, error) {
+	return r.in.readLong()
+}
+
+// ReadFloat reads a float written by DataOutput#writeFloat.
+func (r *BlockDataReader) ReadFloat() (float32, error) {
+	return r.in.readFloat()
+}
+
+// ReadDouble reads a double written by DataOutput#writeDouble.
+func (r *BlockDataReader) ReadDouble() (float64, error) {
+	return r.in.readDouble()
+}
+
+// ReadUTF reads a string written by DataOutput#writeUTF.
+func (r *BlockDataReader) ReadUTF() (string, error) {
+	return r.in.readUTF()
+}
+
+// ReadObject returns the next object. It fails if unread block data precedes the object and returns io.EOF once all
+// annotations have been consumed.
+func (r *BlockDataReader) ReadObject() (interface{}, error) {
+	if len(r.block) > 0 {
+		return nil, errors.Errorf("%d bytes of block data found where an object was expected", len(r.block))
+	}
+
+	for ; r.next < len(r.anns); r.next++ {
+		block, isBlock := r.anns[r.next].([]byte)
+		if !isBlock {
+			break
+		}
+
+		if len(block) > 0 {
+			return nil, errors.Errorf("%d bytes of block data found where an object was expected", len(block))
+		}
+	}
+
+	if r.next >= len(r.anns) {
+		return nil, io.EOF
+	}
+
+	obj := r.anns[r.next]
+	r.next++
+
+	return obj, nil
+}
+
+// readPairs reads exactly cnt key/value pairs as 2*cnt objects.
+func (r *BlockDataReader) readPairs(cnt int32) ([]interface{}, error) {
+	if cnt < 0 || cnt > math.MaxInt32/2 {
+		return nil, errors.Errorf("invalid number of pairs: %d", cnt)
+	}
+
+	return r.readObjects(2 * cnt)
+}
+
+// readObjects reads exactly cnt objects.
+func (r *BlockDataReader) readObjects(cnt int32) (objs []interface{}, err error) {
+	if cnt < 0 {
+		return nil, errors.Errorf("invalid number of elements: %d", cnt)
+	}
+
+	size := int(cnt)
+	if remaining := len(r.anns) - r.next; size > remaining {
+		return nil, errors.Errorf("incorrect number of elements: want %d got %d", size, remaining)
+	}
+
+	objs = make([]interface{}, size)
+
+	for i := range objs {
+		if objs[i], err = r.ReadObject(); err != nil {
+			if err == io.EOF {
+				err = errors.Errorf("incorrect number of elements: want %d got %d", size, i)
+			}
+
+			return nil, err
+		}
+	}
+
+	return objs, nil
+}
@@ -0,0 +1,103 @@
+package jserial
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestBlockDataReaderSpansBlocks(t *testing.T) {
+	r := newBlockDataReader(map[string]interface{}{}, []interface{}{
+		[]byte{0x00, 0x00}, []byte{}, []byte{0x00, 0x7b, 0x00, 0x03}, []byte{'f', 'o', 'o', 0x01}, "bar",
+	})
+	if i, err := r.ReadInt(); err != nil || i != 123 {
+		t.Fail()
+	}
+	if s, err := r.ReadUTF(); err != nil || s != "foo" {
+		t.Fail()
+	}
+	if _, err := r.ReadObject(); err == nil {
+		t.Fail()
+	}
+	if b, err := r.ReadBoolean(); err != nil || !b {
+		t.Fail()
+	}
+	if obj, err := r.ReadObject(); err != nil || obj != "bar" {
+		t.Fail()
+	}
+	if _, err := r.ReadObject(); err != io.EOF {
+		t.Fail()
+	}
+	if _, err := r.ReadInt(); errors.Cause(err) != io.EOF {
+		t.Fail()
+	}
+}
+
+func TestBlockDataReaderObjectInsteadOfData(t *testing.T) {
+	r := newBlockDataReader(map[string]interface{}{}, []interface{}{[]byte{0x00, 0x00}, "foo"})
+	if _, err := r.ReadInt(); err == nil || errors.Cause(err) == io.EOF {
+		t.Fail()
+	}
+}
+
+func TestBlockDataReaderCustom(t *testing.T) {
+	obj, err := ParseSerializedObject(objs["custom"])
+	if err != nil || len(obj) != 3 {
+		t.FailNow()
+	}
+	m := obj[1].(map[string]interface{})
+	anns := m["@"].([]interface{})
+	r := newBlockDataReader(m, anns)
+	if r.DefaultFields()["foo"] != int32(12345) {
+		t.Fail()
+	}
+	if l, err := r.ReadLong(); err != nil || l != -5338123452242252544 {
+		t.Fail()
+	}
+	if s, err := r.ReadShort(); err != nil || s != -18965 {
+		t.Fail()
+	}
+	if b, err := r.ReadByte(); err != nil || b != 0x2d {
+		t.Fail()
+	}
+	if obj, err := r.ReadObject(); err != nil || obj != "and more" {
+		t.Fail()
+	}
+}
+
+func TestDecodeModifiedUTF8(t *testing.T) {
+	s, err := decodeModifiedUTF8([]byte{'a', 0xc0, 0x80, 0xe1, 0x88, 0xb4, 0xed, 0xa0, 0xbd, 0xed, 0xb8, 0x80})
+	if err != nil || s != "a\x00ሴ😀" {
+		t.Fail()
+	}
+	if _, err := decodeModifiedUTF8([]byte{0xe1, 0x88}); err == nil {
+		t.Fail()
+	}
+}
+
+func TestBlockDataReaderPairs(t *testing.T) {
+	r := newBlockDataReader(map[string]interface{}{}, []interface{}{"a", "b"})
+	// a negative size must not wrap around to a valid number of objects
+	if _, err := r.readPairs(-0x7fffffff); err == nil {
+		t.Fail()
+	}
+	if _, err := r.readPairs(0x40000000); err == nil {
+		t.Fail()
+	}
+	if pairs, err := r.readPairs(1); err != nil || len(pairs) != 2 {
+		t.Fail()
+	}
+}
+
+func TestAdaptLegacyPostProc(t *testing.T) {
+	proc := AdaptLegacyPostProc(func(fields map[string]interface{}, anns []interface{}) (map[string]interface{}, error) {
+		fields["value"] = anns[1]
+		return fields, nil
+	})
+
+	data, err := proc(newBlockDataReader(map[string]interface{}{}, []interface{}{[]byte{0x01}, "foo"}))
+	if err != nil || data["value"] != "foo" {
+		t.Fail()
+	}
+}
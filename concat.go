@@ -0,0 +1,46 @@
+package jserial
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// streamHeader is the STREAM_MAGIC and STREAM_VERSION written by every ObjectOutputStream.
+var streamHeader = []byte{0xac, 0xed, 0x00, 0x05}
+
+// SetConcatenatedStreams makes the parser accept streams built by appending the output of several
+// ObjectOutputStreams, e.g. to a file. A stream header found between top level objects starts a new sub stream with
+// an empty handle table, SubStreams reports which sub stream each object came from.
+func SetConcatenatedStreams(concatenated bool) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.concatenated = concatenated
+	}
+}
+
+// SubStreams returns the index of the sub stream each top level object read so far came from, it only contains
+// zeros unless SetConcatenatedStreams is used.
+func (sop *SerializedObjectParser) SubStreams() []int {
+	return sop.subStreams
+}
+
+// nextSubStream starts a new sub stream if a stream header follows.
+func (sop *SerializedObjectParser) nextSubStream() error {
+	if !sop.concatenated || sop.stopped || sop.hasPeeked() {
+		return nil
+	}
+
+	for {
+		if b, err := sop.rd.Peek(len(streamHeader)); err != nil || !bytes.Equal(b, streamHeader) {
+			return nil
+		}
+
+		if _, err := io.ReadFull(sop.rd, make([]byte, len(streamHeader))); err != nil {
+			return errors.Wrap(err, "error reading stream header")
+		}
+
+		sop.handles, sop.resynced = nil, false
+		sop.subStream++
+	}
+}
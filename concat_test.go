@@ -0,0 +1,45 @@
+package jserial
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConcatenatedStreams(t *testing.T) {
+	header := streamMagic + streamVersion
+	// the reference is to the first handle of the second stream
+	hexStr := header + tcString + encodeStr("foo") + header + tcString + encodeStr("bar") + tcReference +
+		"00" + baseWireHandle + header + header
+
+	sop := newTestParser(t, hexStr, SetConcatenatedStreams(true))
+	content, err := sop.ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !reflect.DeepEqual(content, []interface{}{"foo", "bar", "bar"}) {
+		t.Errorf("unexpected content %v", content)
+	}
+	if subStreams := sop.SubStreams(); !reflect.DeepEqual(subStreams, []int{0, 1, 1}) {
+		t.Errorf("unexpected sub streams %v", subStreams)
+	}
+
+	_, err = newTestParser(t, hexStr).ParseSerializedObject()
+	if err == nil || !strings.Contains(err.Error(), "unknown type 0xac") {
+		t.Errorf("unexpected error without concatenated streams: %v", err)
+	}
+}
+
+func TestConcatenatedStreamsHandleReset(t *testing.T) {
+	header := streamMagic + streamVersion
+	hexStr := header + tcString + encodeStr("foo") + header + tcReference + "00" + baseWireHandle
+
+	// references cannot be resolved across streams
+	content, err := newTestParser(t, hexStr, SetConcatenatedStreams(true)).ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !reflect.DeepEqual(content, []interface{}{"foo", nil}) {
+		t.Errorf("unexpected content %v", content)
+	}
+}
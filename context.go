@@ -0,0 +1,143 @@
+package jserial
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// contextCheckInterval is the number of values read between checks of the parser's context.
+const contextCheckInterval = 256
+
+// ParseSerializedObjectContext parses a serialized java object, stopping when ctx is done. Parsers are pooled, so
+// parsing many small objects causes little garbage.
+func ParseSerializedObjectContext(ctx context.Context, buf []byte) (content []interface{}, err error) {
+	sop := acquireParser(buf)
+	defer releaseParser(sop)
+
+	return sop.ParseSerializedObjectContext(ctx)
+}
+
+// ParseSerializedObjectContext parses a serialized java object from stream, stopping when ctx is done. The context is
+// checked periodically while values are read, a read blocked on the underlying reader is not interrupted.
+func (sop *SerializedObjectParser) ParseSerializedObjectContext(ctx context.Context) (content []interface{},
+	err error) {
+	for {
+		var nxt interface{}
+
+		if nxt, err = sop.NextContext(ctx); err != nil {
+			if err == io.EOF {
+				err = nil
+			} else if nxt != nil {
+				// the partially read object of a truncated stream
+				content = append(content, nxt)
+			}
+
+			return
+		}
+
+		content = append(content, nxt)
+	}
+}
+
+// Next reads the next top level content (e.g. an object or block data) of the stream and returns io.EOF at its end.
+func (sop *SerializedObjectParser) Next() (interface{}, error) {
+	return sop.NextContext(context.Background())
+}
+
+// NextContext is like Next but stops when ctx is done, returning ctx.Err() wrapped with the position in the stream.
+func (sop *SerializedObjectParser) NextContext(ctx context.Context) (nxt interface{}, err error) {
+	sop.ctx = ctx
+
+	nxt, err = sop.next()
+
+	sop.ctx = nil
+
+	return
+}
+
+func (sop *SerializedObjectParser) next() (nxt interface{}, err error) {
+	if err = sop.contextErr(); err != nil {
+		return
+	}
+
+	if err = sop.header(); err != nil {
+		return nil, sop.parseError(err)
+	}
+
+	defer func() {
+		if err == nil || nxt != nil {
+			sop.subStreams = append(sop.subStreams, sop.subStream)
+		}
+	}()
+
+	// return any block data left over by the ObjectInputStream style methods first
+	if len(sop.block) > 0 {
+		nxt, sop.block = sop.block, nil
+
+		return
+	}
+
+	// return the object found by resynchronizing in lenient mode
+	if sop.hasPending {
+		nxt, sop.pending, sop.hasPending = sop.pending, nil, false
+
+		return
+	}
+
+	if err = sop.nextSubStream(); err != nil {
+		return nil, sop.parseError(err)
+	}
+
+	if sop.stopped || (!sop.hasPeeked() && sop.end()) {
+		return nil, io.EOF
+	}
+
+	sop.resetPath()
+
+	offset := sop.rd.pos
+	if sop.hasPeeked() {
+		offset = sop.peeked.offset
+	}
+
+	if nxt, err = sop.content(nil); err != nil {
+		if sop.partial(err) {
+			return nxt, sop.parseError(err)
+		}
+
+		if !sop.recoverable(err) {
+			return nil, sop.parseError(err)
+		}
+
+		return sop.recover(offset, err)
+	}
+
+	return
+}
+
+// checkContext periodically checks whether the parser's context is done.
+func (sop *SerializedObjectParser) checkContext() error {
+	if sop.ctx == nil {
+		return nil
+	}
+
+	if sop.ctxTicks++; sop.ctxTicks%contextCheckInterval != 0 {
+		return nil
+	}
+
+	return sop.contextErr()
+}
+
+// contextErr returns the error of the parser's context, if it is done, wrapped with the position in the stream.
+func (sop *SerializedObjectParser) contextErr() error {
+	if sop.ctx == nil {
+		return nil
+	}
+
+	if err := sop.ctx.Err(); err != nil {
+		return errors.Wrapf(err, "parsing stopped at offset %d", sop.rd.pos)
+	}
+
+	return nil
+}
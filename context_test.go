@@ -0,0 +1,64 @@
+package jserial
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cancelReader cancels a context once the first read has been made.
+type cancelReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (cr *cancelReader) Read(p []byte) (int, error) {
+	cr.cancel()
+	return cr.r.Read(p)
+}
+
+func TestParseContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ParseSerializedObjectContext(ctx, objs["hashMapStr"]); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func TestParseContextCanceledWhileParsing(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("fuzzdata", "corpus", "limit_handles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sop := NewSerializedObjectParser(&cancelReader{r: bytes.NewReader(b), cancel: cancel})
+	_, err = sop.ParseSerializedObjectContext(ctx)
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "parsing stopped at offset") {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	if sop.rd.pos == 0 || sop.rd.pos >= int64(len(b)) {
+		t.Errorf("unexpected position %d", sop.rd.pos)
+	}
+}
+
+func TestNext(t *testing.T) {
+	sop := newTestParser(t, streamMagic+streamVersion+tcString+encodeStr("foo")+tcBlockData+"01"+"2a"+tcNull)
+	for _, want := range []interface{}{"foo", []byte{0x2a}, nil} {
+		got, err := sop.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	if _, err := sop.Next(); err != io.EOF {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package jserial
+
+import (
+	"encoding/binary"
+	"math"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+// dataInput decodes java.io.DataInput primitives from the bytes supplied by readFully.
+type dataInput struct {
+	readFully func(p []byte) error
+	scratch   [8]byte
+}
+
+func (in *dataInput) read(n int) ([]byte, error) {
+	b := in.scratch[:n]
+	if err := in.readFully(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (in *dataInput) readBoolean() (bool, error) {
+	b, err := in.read(1)
+	if err != nil {
+		return false, errors.Wrap(err, "error reading boolean")
+	}
+
+	return b[0] != 0, nil
+}
+
+func (in *dataInput) readByte() (byte, error) {
+	b, err := in.read(1)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading byte")
+	}
+
+	return b[0], nil
+}
+
+func (in *dataInput) readUnsignedShort() (uint16, error) {
+	b, err := in.read(2)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading short")
+	}
+
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (in *dataInput) readInt() (int32, error) {
+	b, err := in.read(4)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading int")
+	}
+
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (in *dataInput) readLong() (int64, error) {
+	b, err := in.read(8)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading long")
+	}
+
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (in *dataInput) readFloat() (float32, error) {
+	b, err := in.read(4)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading float")
+	}
+
+	return math.Float32frombits(binary.BigEndian.Uint32(b)), nil
+}
+
+func (in *dataInput) readDouble() (float64, error) {
+	b, err := in.read(8)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading double")
+	}
+
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+func (in *dataInput) readUTF() (string, error) {
+	size, err := in.readUnsignedShort()
+	if err != nil {
+		return "", errors.Wrap(err, "error reading utf length")
+	}
+
+	b := make([]byte, size)
+	if err = in.readFully(b); err != nil {
+		return "", errors.Wrap(err, "error reading utf")
+	}
+
+	return decodeModifiedUTF8(b)
+}
+
+// decodeModifiedUTF8 decodes the "modified UTF-8" encoding used by java.io.DataOutput#writeUTF.
+// see: https://docs.oracle.com/javase/8/docs/api/java/io/DataInput.html#modified-utf-8
+func decodeModifiedUTF8(b []byte) (string, error) {
+	chars := make([]uint16, 0, len(b))
+
+	for i := 0; i < len(b); {
+		c := b[i]
+
+		switch {
+		case c < 0x80:
+			chars = append(chars, uint16(c))
+			i++
+		case c&0xe0 == 0xc0:
+			if i+1 >= len(b) || b[i+1]&0xc0 != 0x80 {
+				return "", errors.Errorf("malformed utf input around byte %d", i)
+			}
+
+			chars = append(chars, uint16(c&0x1f)<<6|uint16(b[i+1]&0x3f))
+			i += 2
+		case c&0xf0 == 0xe0:
+			if i+2 >= len(b) || b[i+1]&0xc0 != 0x80 || b[i+2]&0xc0 != 0x80 {
+				return "", errors.Errorf("malformed utf input around byte %d", i)
+			}
+
+			chars = append(chars, uint16(c&0x0f)<<12|uint16(b[i+1]&0x3f)<<6|uint16(b[i+2]&0x3f))
+			i += 3
+		default:
+			return "", errors.Errorf("malformed utf input around byte %d", i)
+		}
+	}
+
+	return string(utf16.Decode(chars)), nil
+}
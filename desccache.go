@@ -0,0 +1,360 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultDescriptorCacheSize is the number of descriptors held by a DescriptorCache created with a non-positive size.
+const defaultDescriptorCacheSize = 1024
+
+// maxInternedVariants is the number of superclasses a descriptor is interned with.
+const maxInternedVariants = 8
+
+// DescriptorCache holds the class descriptors read by the parsers sharing it, so streams of the same classes, e.g.
+// many small messages, neither parse nor allocate their descriptors again. A descriptor is reused when the bytes of its
+// name, serialVersionUID, flags and fields match, and descriptors without annotations are interned: parsers return the
+// same class for each of them along with a precomputed post processor lookup. A DescriptorCache is safe for concurrent
+// use, descriptors are added until it is full and are never evicted.
+type DescriptorCache struct {
+	mu         sync.RWMutex
+	maxEntries int
+	entries    map[string][]*descEntry
+	size       int
+}
+
+// descEntry is a cached class descriptor.
+type descEntry struct {
+	// own holds the bytes of the name, serialVersionUID, flags and fields of the descriptor.
+	own []byte
+	// cls holds the name, serialVersionUID, flags and fields of the descriptor.
+	cls *clazz
+	// strings are the field class names assigned a handle, in stream order.
+	strings []string
+	// refs are the field class names referring to an earlier handle.
+	refs []descRef
+	// variants are the interned classes by superclass, guarded by the cache's mutex.
+	variants map[*clazz]*clazz
+}
+
+// descRef is a reference to a handle expected to hold a string.
+type descRef struct {
+	handle int
+	value  string
+}
+
+// procMemo is the post processor found for an interned class in a registry snapshot.
+type procMemo struct {
+	table *registryTable
+	proc  PostProc
+}
+
+// NewDescriptorCache returns a DescriptorCache holding up to maxEntries descriptors, 1024 if not positive.
+func NewDescriptorCache(maxEntries int) *DescriptorCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultDescriptorCacheSize
+	}
+
+	return &DescriptorCache{maxEntries: maxEntries, entries: map[string][]*descEntry{}}
+}
+
+// WithDescriptorCache makes the parser look up and add class descriptors in cache. To find a cached descriptor the
+// parser peeks ahead up to its length, so the cache is not used with SetExactReads, nor when recording spans.
+func WithDescriptorCache(cache *DescriptorCache) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.descCache = cache
+	}
+}
+
+// Len returns the number of cached descriptors.
+func (c *DescriptorCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.size
+}
+
+// lookup returns the descriptors starting with the name and serialVersionUID in head.
+func (c *DescriptorCache) lookup(head []byte) []*descEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.entries[string(head)]
+}
+
+// add caches entry unless the cache is full or holds the same descriptor, i.e. with the same bytes referring to the
+// same strings, which is returned instead.
+func (c *DescriptorCache) add(head []byte, entry *descEntry) *descEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cached := range c.entries[string(head)] {
+		if bytes.Equal(cached.own, entry.own) && reflect.DeepEqual(cached.refs, entry.refs) {
+			return cached
+		}
+	}
+
+	if c.size >= c.maxEntries {
+		return nil
+	}
+
+	c.entries[string(head)] = append(c.entries[string(head)], entry)
+	c.size++
+
+	return entry
+}
+
+// intern returns the class shared by the descriptors of entry with the superclass of cls, or cls if there is none.
+func (c *DescriptorCache) intern(entry *descEntry, cls *clazz) *clazz {
+	c.mu.RLock()
+	interned := entry.variants[cls.super]
+	c.mu.RUnlock()
+
+	if interned != nil {
+		return interned
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if interned = entry.variants[cls.super]; interned != nil {
+		return interned
+	}
+
+	if len(entry.variants) >= maxInternedVariants {
+		return cls
+	}
+
+	// the cached strings are safe to share, unlike those of cls with SetUnsafeStrings
+	interned = &clazz{}
+	*interned = *entry.cls
+	interned.super = cls.super
+	interned.memo = &atomic.Value{}
+
+	if entry.variants == nil {
+		entry.variants = map[*clazz]*clazz{}
+	}
+
+	entry.variants[cls.super] = interned
+
+	return interned
+}
+
+// usesDescCache reports whether class descriptors are looked up in a cache.
+func (sop *SerializedObjectParser) usesDescCache() bool {
+	return sop.descCache != nil && !sop.recordSpans && !sop.exactReads
+}
+
+// cachedClassDesc reads the name, serialVersionUID, flags and fields of the class descriptor starting at offset from
+// the cache if the next bytes of the stream match a cached descriptor. The handles of the descriptor are assigned like
+// when parsing it, handle being the index of the class.
+func (sop *SerializedObjectParser) cachedClassDesc(offset int64) (cls *clazz, handle int, entry *descEntry,
+	err error) {
+	head := sop.peekDescHead()
+	if head == nil {
+		return
+	}
+
+	for _, candidate := range sop.descCache.lookup(head) {
+		if own, _ := sop.rd.Peek(len(candidate.own)); bytes.Equal(own, candidate.own) && sop.matchRefs(candidate) {
+			entry = candidate
+
+			break
+		}
+	}
+
+	if entry == nil {
+		return
+	}
+
+	// the checks done while parsing the descriptor still apply
+	if err = sop.checkCachedStrings(entry); err != nil {
+		return
+	}
+
+	if sop.classHook != nil {
+		sop.classHook(entry.cls.name, offset)
+	}
+
+	if err = sop.checkFilter(entry.cls.name, -1); err != nil {
+		return
+	}
+
+	if err = sop.checkInput(int64(len(entry.own))); err != nil {
+		return
+	}
+
+	own, _ := sop.rd.Peek(len(entry.own))
+	if err = sop.rd.discard(own); err != nil {
+		return
+	}
+
+	cls = &clazz{}
+	*cls = *entry.cls
+	handle = len(sop.handles)
+
+	if _, err = sop.newHandle(cls); err != nil {
+		return
+	}
+
+	for _, str := range entry.strings {
+		if _, err = sop.newHandle(str); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// checkCachedStrings ensures the class name, field names and field class names of entry are allowed by the string
+// length limit, like when they are read from the stream.
+func (sop *SerializedObjectParser) checkCachedStrings(entry *descEntry) error {
+	if err := sop.checkStringLength(int64(len(entry.cls.name))); err != nil {
+		return err
+	}
+
+	for _, f := range entry.cls.fields {
+		if err := sop.checkStringLength(int64(len(f.name))); err != nil {
+			return err
+		}
+	}
+
+	for _, str := range entry.strings {
+		if err := sop.checkStringLength(int64(len(str))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// peekDescHead returns the next bytes of the stream holding the name and serialVersionUID of a class descriptor.
+func (sop *SerializedObjectParser) peekDescHead() []byte {
+	b, err := sop.rd.Peek(2)
+	if err != nil {
+		return nil
+	}
+
+	if b, err = sop.rd.Peek(descHeadLen(b)); err != nil {
+		return nil
+	}
+
+	return b
+}
+
+// matchRefs reports whether the handles referred to by the field class names of entry hold the same strings.
+func (sop *SerializedObjectParser) matchRefs(entry *descEntry) bool {
+	for _, ref := range entry.refs {
+		i := ref.handle - sop.handleBase
+		if i < 0 || i >= len(sop.handles) || !sop.knownHandle(i) {
+			return false
+		}
+
+		if str, isString := sop.handles[i].(string); !isString || str != ref.value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cacheClassDesc adds the descriptor cls read from the bytes own to the cache and returns its entry, or nil if it
+// cannot be cached.
+func (sop *SerializedObjectParser) cacheClassDesc(cls *clazz, own []byte) *descEntry {
+	entry := newDescEntry(cls, own)
+	if entry == nil {
+		return nil
+	}
+
+	return sop.descCache.add(entry.own[:descHeadLen(own)], entry)
+}
+
+// descHeadLen returns the length of the name and serialVersionUID at the start of b.
+func descHeadLen(b []byte) int {
+	const serialVersionUIDLength = 8
+
+	return 2 + int(binary.BigEndian.Uint16(b)) + serialVersionUIDLength
+}
+
+// newDescEntry returns the entry of the descriptor cls read from the bytes own, or nil if its field class names are
+// neither new strings nor references.
+func newDescEntry(cls *clazz, own []byte) *descEntry {
+	const (
+		tcReference = 0x71
+		tcString    = 0x74
+		refIDMask   = 0x7e0000
+	)
+
+	entry := &descEntry{
+		own: append([]byte(nil), own...),
+		cls: &clazz{
+			name:             cloneString(cls.name),
+			serialVersionUID: cloneString(cls.serialVersionUID),
+			flags:            cls.flags,
+			isEnum:           cls.isEnum,
+			fields:           make([]*field, len(cls.fields)),
+		},
+	}
+
+	entry.cls.sig = entry.cls.name + "@" + entry.cls.serialVersionUID
+
+	// skip the flags and field count
+	p := descHeadLen(own) + 1 + 2
+
+	for i, f := range cls.fields {
+		entry.cls.fields[i] = &field{className: cloneString(f.className), typeName: f.typeName, name: cloneString(f.name)}
+
+		// skip the type and name
+		p += 1 + 2 + int(binary.BigEndian.Uint16(own[p+1:]))
+
+		if f.typeName != "[" && f.typeName != "L" {
+			continue
+		}
+
+		switch own[p] {
+		case tcString:
+			entry.strings = append(entry.strings, entry.cls.fields[i].className)
+			p += 1 + 2 + int(binary.BigEndian.Uint16(own[p+1:]))
+		case tcReference:
+			handle := int(binary.BigEndian.Uint32(own[p+1:])) - refIDMask
+			entry.refs = append(entry.refs, descRef{handle: handle, value: entry.cls.fields[i].className})
+			p += 1 + 4
+		default:
+			return nil
+		}
+	}
+
+	return entry
+}
+
+// internClass returns the interned class for cls, read from entry and assigned handle, replacing it in the handle
+// table. Classes with annotations or a superclass which is not interned are not interned.
+func (sop *SerializedObjectParser) internClass(entry *descEntry, cls *clazz, handle int) *clazz {
+	if entry == nil || cls.annotations != nil || (cls.super != nil && cls.super.memo == nil) {
+		return cls
+	}
+
+	interned := sop.descCache.intern(entry, cls)
+	if interned != cls && handle < len(sop.handles) && sop.handles[handle] == cls {
+		sop.handles[handle] = interned
+	}
+
+	return interned
+}
+
+// signature returns the `name@suid` signature of the class.
+func (cls *clazz) signature() string {
+	if cls.sig != "" {
+		return cls.sig
+	}
+
+	return cls.name + "@" + cls.serialVersionUID
+}
+
+// cloneString returns a copy of s which does not share memory with the input of the parser.
+func cloneString(s string) string {
+	return string(append([]byte(nil), s...))
+}
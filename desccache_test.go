@@ -0,0 +1,188 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func parseWithCache(t *testing.T, b []byte, cache *DescriptorCache, options ...Option) []interface{} {
+	options = append([]Option{WithDescriptorCache(cache)}, options...)
+	content, err := NewSerializedObjectParserBytes(b, options...).ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	return content
+}
+
+func TestDescriptorCache(t *testing.T) {
+	names := make([]string, 0, len(objs))
+	for name := range objs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cache := NewDescriptorCache(0)
+
+	// the second pass reads the descriptors from the cache
+	for pass := 0; pass < 2; pass++ {
+		for _, name := range names {
+			expected, err := ParseSerializedObject(objs[name])
+			if err != nil {
+				continue
+			}
+			// interned classes hold precomputed lookups, so only the minimal representations are equal
+			content := jsonFriendlyArray(parseWithCache(t, objs[name], cache))
+			if expected = jsonFriendlyArray(expected); !reflect.DeepEqual(content, expected) {
+				t.Errorf("%s: unexpected content %v != %v", name, content, expected)
+			}
+		}
+	}
+
+	if cache.Len() == 0 {
+		t.Error("expected cached descriptors")
+	}
+}
+
+func TestDescriptorCacheInterning(t *testing.T) {
+	cache := NewDescriptorCache(0)
+	b := decodeHex(t, streamHex("", ""))
+
+	first := parseWithCache(t, b, cache)[0].(map[string]interface{})
+	second := parseWithCache(t, b, cache)[0].(map[string]interface{})
+	if first["class"] != second["class"] {
+		t.Error("expected the same class for both streams")
+	}
+	if first["foo"] != second["foo"] || cache.Len() != 1 {
+		t.Errorf("unexpected content %v, %d cached descriptors", second, cache.Len())
+	}
+
+	// other parsers are not affected
+	if parseWithCache(t, b, NewDescriptorCache(0))[0].(map[string]interface{})["class"] == first["class"] {
+		t.Error("unexpected class shared across caches")
+	}
+}
+
+func TestDescriptorCacheFieldReferences(t *testing.T) {
+	// the class name of the field refers to the string before the descriptor
+	streamOf := func(className string) []byte {
+		return decodeHex(t, streamMagic+streamVersion+tcString+encodeStr(className)+tcObject+tcClassDesc+
+			someClassEnc+serialVer+scSerializable+"0001"+hex.EncodeToString([]byte("L"))+fooEnc+tcReference+"00"+
+			baseWireHandle+tcEndBlockData+tcNull+tcNull)
+	}
+
+	cache := NewDescriptorCache(0)
+	for _, className := range []string{"Lfoo;", "Lbar;", "Lfoo;"} {
+		sop := NewSerializedObjectParserBytes(streamOf(className), WithDescriptorCache(cache))
+		if _, err := sop.ParseSerializedObject(); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if fields := sop.handles[1].(*clazz).fields; fields[0].className != className {
+			t.Errorf("unexpected field class name %s instead of %s", fields[0].className, className)
+		}
+	}
+
+	if cache.Len() != 2 {
+		t.Errorf("unexpected number of cached descriptors %d", cache.Len())
+	}
+}
+
+func TestDescriptorCacheRegistry(t *testing.T) {
+	cache := NewDescriptorCache(0)
+	registry := &Registry{}
+	if err := registry.Register("java.util.Date", valuePostProc("first")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"first", "first", "second"} {
+		if expected == "second" {
+			if err := registry.Register("java.util.Date", valuePostProc(expected)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		content := parseWithCache(t, objs["date"], cache, WithPostProcs(registry))
+		if val := jsonFriendlyArray(content)[1]; val != expected {
+			t.Errorf("unexpected value %v instead of %s", val, expected)
+		}
+	}
+}
+
+func TestDescriptorCacheStringLength(t *testing.T) {
+	cache := NewDescriptorCache(0)
+	b := decodeHex(t, streamHex("", ""))
+	parseWithCache(t, b, cache)
+
+	// the name of a cached class is still checked against the limit of the parser
+	_, err := NewSerializedObjectParserBytes(b, WithDescriptorCache(cache), SetMaxStringLength(8)).ParseSerializedObject()
+	if !errors.Is(err, ErrMaxStringLength) {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	parseWithCache(t, b, cache, SetMaxStringLength(len("SomeClass")))
+}
+
+func TestDescriptorCacheSize(t *testing.T) {
+	cache := NewDescriptorCache(1)
+	parseWithCache(t, objs["primArray"], cache)
+	parseWithCache(t, objs["primArray"], cache)
+	if cache.Len() != 1 {
+		t.Errorf("unexpected number of cached descriptors %d", cache.Len())
+	}
+}
+
+func TestDescriptorCacheConcurrent(t *testing.T) {
+	cache := NewDescriptorCache(0)
+	b := objs["hashMapStr"]
+	expected, err := ParseSerializedObject(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				content, err := NewSerializedObjectParser(bytes.NewReader(b), WithDescriptorCache(cache)).
+					ParseSerializedObject()
+				if err != nil || !reflect.DeepEqual(jsonFriendlyArray(content), jsonFriendlyArray(expected)) {
+					t.Errorf("unexpected content %v, error: %+v", content, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkDescriptorCache(b *testing.B) {
+	buf := objs["hashMapStr"]
+
+	for _, bc := range []struct {
+		name    string
+		options []Option
+	}{
+		{"none", nil},
+		{"cache", []Option{WithDescriptorCache(NewDescriptorCache(0))}},
+		{"registry", []Option{WithPostProcs(NewRegistry())}},
+		{"registry_cache", []Option{WithPostProcs(NewRegistry()), WithDescriptorCache(NewDescriptorCache(0))}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			sop := NewSerializedObjectParserBytes(nil, bc.options...)
+			b.SetBytes(int64(len(buf)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				sop.ResetBytes(buf)
+				if _, err := sop.ParseSerializedObject(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -3,10 +3,13 @@ package jserial
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/hex"
 	"io"
+	"math"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -14,37 +17,12 @@ import (
 
 // ParseSerializedObject parses a serialized java object.
 func ParseSerializedObject(buf []byte) (content []interface{}, err error) {
-	option := SetMaxDataBlockSize(len(buf))
-	sop := NewSerializedObjectParser(bytes.NewReader(buf), option)
-
-	return sop.ParseSerializedObject()
+	return ParseSerializedObjectContext(context.Background(), buf)
 }
 
 // ParseSerializedObject parses a serialized java object from stream.
 func (sop *SerializedObjectParser) ParseSerializedObject() (content []interface{}, err error) {
-	if err = sop.magic(); err != nil {
-		return
-	}
-
-	if err = sop.version(); err != nil {
-		return
-	}
-
-	for !sop.end() {
-		var nxt interface{}
-
-		if nxt, err = sop.content(nil); err != nil {
-			if errors.Cause(err).Error() == io.EOF.Error() {
-				err = errors.New("premature end of input")
-			}
-
-			return
-		}
-
-		content = append(content, nxt)
-	}
-
-	return
+	return sop.ParseSerializedObjectContext(context.Background())
 }
 
 // ParseSerializedObjectMinimal parses a serialized java object and returns the minimal object representation
@@ -60,8 +38,14 @@ func ParseSerializedObjectMinimal(buf []byte) (content []interface{}, err error)
 // ParseSerializedObjectMinimal parses a serialized java object from stream
 // and returns the minimal object representation (i.e. without all the class info, etc...).
 func (sop *SerializedObjectParser) ParseSerializedObjectMinimal() (content []interface{}, err error) {
-	if content, err = sop.ParseSerializedObject(); err == nil {
+	content, err = sop.ParseSerializedObject()
+
+	switch {
+	case err == nil, sop.partialResults && content != nil:
+		// partial results are converted as well
 		content = jsonFriendlyArray(content)
+	case err != nil:
+		content = nil
 	}
 
 	return
@@ -90,6 +74,12 @@ func jsonFriendlyObject(obj interface{}) (jsonObj interface{}) {
 		return
 	}
 
+	if partial, isPartial := obj.(*PartialArray); isPartial {
+		jsonObj = &PartialArray{Elements: jsonFriendlyObject(partial.Elements), Length: partial.Length}
+
+		return
+	}
+
 	// default for raw / primitive fields
 	return obj
 }
@@ -158,6 +148,9 @@ var typeNames = []string{
 	"Enum",
 }
 
+// typeMask is subtracted from a type code to get its index in typeNames.
+const typeMask = 0x70
+
 // typeNameMax is used to ensure an encountered type is known.
 var typeNameMax = uint8(len(typeNames) - 1)
 
@@ -175,23 +168,39 @@ type parser func(sop *SerializedObjectParser) (interface{}, error)
 // knownParsers maps serialized names to corresponding parser implementations.
 var knownParsers map[string]parser
 
-// PostProc handlers are used to format deserialized objects for easier consumption.
-type PostProc func(map[string]interface{}, []interface{}) (map[string]interface{}, error)
-
-// KnownPostProcs maps serialized object signatures to PostProc implementations.
-var KnownPostProcs = map[string]PostProc{
-	"java.util.ArrayList@7881d21d99c7619d":  listPostProc,
-	"java.util.ArrayDeque@207cda2e240da08b": listPostProc,
-	"java.util.Hashtable@13bb0f25214ae4b8":  mapPostProc,
-	"java.util.HashMap@0507dac1c31660d1":    mapPostProc,
-	"java.util.EnumMap@065d7df7be907ca1":    enumMapPostProc,
-	"java.util.HashSet@ba44859596b8b734":    hashSetPostProc,
-	"java.util.Date@686a81014b597419":       datePostProc,
+// PostProc handlers are used to format deserialized objects for easier consumption. They read the data written by
+// the class's custom serialization method from the BlockDataReader and return the object representation, usually
+// the reader's DefaultFields with an added "value".
+type PostProc func(r *BlockDataReader) (map[string]interface{}, error)
+
+// defaultPostProcs returns the built-in PostProc implementations keyed by serialized object signature.
+func defaultPostProcs() map[string]PostProc {
+	return map[string]PostProc{
+		"java.util.ArrayList@7881d21d99c7619d":  listPostProc,
+		"java.util.ArrayDeque@207cda2e240da08b": listPostProc,
+		"java.util.Hashtable@13bb0f25214ae4b8":  mapPostProc,
+		"java.util.HashMap@0507dac1c31660d1":    mapPostProc,
+		"java.util.EnumMap@065d7df7be907ca1":    enumMapPostProc,
+		"java.util.HashSet@ba44859596b8b734":    hashSetPostProc,
+		"java.util.Date@686a81014b597419":       datePostProc,
+	}
 }
 
+// KnownPostProcs maps serialized object signatures to PostProc implementations. It is the default used by parsers
+// created without the WithPostProcs option.
+//
+// Deprecated: mutating KnownPostProcs is not safe while other goroutines are parsing. Build a Registry and pass it
+// to NewSerializedObjectParser with WithPostProcs instead.
+var KnownPostProcs = defaultPostProcs()
+
 // primitiveHandler are used to read primitive values.
 type primitiveHandler func(sop *SerializedObjectParser) (interface{}, error)
 
+// isObjectType reports whether a field type identifies an object or array rather than a primitive value.
+func isObjectType(typeName string) bool {
+	return typeName == "L" || typeName == "["
+}
+
 // primitiveHandlers maps serialized primitive identifiers to a corresponding primitiveHandler.
 var primitiveHandlers = map[string]primitiveHandler{
 	"B": func(sop *SerializedObjectParser) (b interface{}, err error) {
@@ -275,82 +284,227 @@ var primitiveHandlers = map[string]primitiveHandler{
 // SerializedObjectParser reads serialized java objects
 // see: https://docs.oracle.com/javase/8/docs/platform/serialization/spec/protocol.html
 type SerializedObjectParser struct {
-	buf              bytes.Buffer
-	rd               *bufio.Reader
-	handles          []interface{}
-	maxDataBlockSize int
+	buf            bytes.Buffer
+	rd             *reader
+	handles        []interface{}
+	bufferSize     int
+	postProcs      *Registry
+	filter         *SerialFilter
+	limits         limits
+	headerRead     bool
+	block          []byte
+	in             dataInput
+	depth          int
+	objDepth       int
+	refs           int
+	classHook      func(name string, offset int64)
+	path           []pathSeg
+	typeCodes      []byte
+	ctx            context.Context
+	ctxTicks       int
+	lenient        bool
+	warnings       []*ParseError
+	softErr        error
+	seeker         io.ReadSeeker
+	seekBase       int64
+	pending        interface{}
+	hasPending     bool
+	resynced       bool
+	knownHandles   int
+	stopped        bool
+	partialResults bool
+	recordSpans    bool
+	spans          []Span
+	openSpan       int
+	lastSpan       int
+	spanIndex      map[spanKey]int
+	exactReads     bool
+	concatenated   bool
+	subStream      int
+	subStreams     []int
+	contentOffset  int64
+	peeked         *peekedContent
+	scratch        [8]byte
+	zeroCopy       bool
+	unsafeStrings  bool
+	skipHeader     bool
+	indexing       bool
+	contentStack   []indexEntry
+	indexed        []indexEntry
+	handleBase     int
+	resolveHandle  func(handle int) (interface{}, error)
+	sink           Sink
+	sinkMinSize    int64
+	readingBlock   bool
+	procDepth      int
+	descCache      *DescriptorCache
 }
 
 const bufferSize = 1024
 
 type Option func(sop *SerializedObjectParser)
 
-// SetMaxDataBlockSize set the maximum size of the parsed data block,
-// by default it is equal to the value of the buffer size bufio.Reader or size of bytes.Reader.
+// SetMaxDataBlockSize sets both the maximum length of strings and the maximum size of block data.
+//
+// Deprecated: use SetMaxStringLength and SetMaxBlockDataSize.
 func SetMaxDataBlockSize(maxSize int) Option {
 	return func(sop *SerializedObjectParser) {
-		sop.maxDataBlockSize = maxSize
+		sop.limits.maxStringLength = maxSize
+		sop.limits.maxBlockDataSize = maxSize
+	}
+}
+
+// SetBufferSize sets the size of the buffer used to read ahead from the stream, 1024 bytes by default.
+func SetBufferSize(size int) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.bufferSize = size
+	}
+}
+
+// WithPostProcs sets the registry used to look up post processors. By default KnownPostProcs is used.
+func WithPostProcs(registry *Registry) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.postProcs = registry
 	}
 }
 
 // NewSerializedObjectParser reads serialized java objects from stream.
 func NewSerializedObjectParser(rd io.Reader, options ...Option) *SerializedObjectParser {
-	buf := bufio.NewReaderSize(rd, bufferSize)
 	sop := &SerializedObjectParser{
-		rd:               buf,
-		maxDataBlockSize: buf.Size(),
+		rd:         &reader{},
+		limits:     defaultLimits(),
+		bufferSize: bufferSize,
+		openSpan:   -1,
+		lastSpan:   -1,
 	}
 
+	sop.in.readFully = sop.ReadFully
+
 	for _, option := range options {
 		option(sop)
 	}
 
+	if sop.exactReads {
+		sop.rd.br = newExactSource(rd)
+	} else {
+		sop.rd.br = bufio.NewReaderSize(rd, sop.bufferSize)
+	}
+
+	sop.initSeeker(rd)
+
 	return sop
 }
 
 // newHandle adds a parsed object to the existing indexed handles which can be used later to lookup references to
 // existing objects.
-func (sop *SerializedObjectParser) newHandle(obj interface{}) interface{} {
-	sop.handles = append(sop.handles, obj)
+func (sop *SerializedObjectParser) newHandle(obj interface{}) (interface{}, error) {
+	if err := sop.checkHandles(); err != nil {
+		return nil, err
+	}
 
-	return obj
+	sop.spanHandle(sop.handleBase + len(sop.handles))
+	sop.indexHandle()
+	sop.handles = append(sop.handles, sop.retained(obj))
+	sop.refs++
+
+	return obj, nil
 }
 
 // content reads the next object in the stream and parses it.
 func (sop *SerializedObjectParser) content(allowedNames map[string]bool) (content interface{}, err error) {
 	var tc uint8
 
-	if tc, err = sop.readUInt8(); err != nil {
+	if sop.hasPeeked() {
+		// continue with the content whose class was read by PeekClass
+		tc, sop.contentOffset = sop.peeked.tc, sop.peeked.offset
+		sop.peeked.resumed = true
+	} else if tc, err = sop.readUInt8(); err != nil {
 		return
+	} else {
+		sop.contentOffset = sop.rd.pos - 1
 	}
 
-	const typeMask = 0x70
+	// the type code is left in place when an error occurs to report the content which failed
+	sop.typeCodes = append(sop.typeCodes, tc)
+
 	tc -= typeMask
 
 	if tc > typeNameMax {
 		// prevents reading unknown ("foreign") byte from the stream
 		sop.rd.UnreadByte() //nolint:errcheck
 
-		err = errors.Errorf("unknown type %#x", tc+typeMask)
+		err = tagf(ErrUnknownTypeCode, "unknown type %#x", tc+typeMask)
 
 		return
 	}
 
 	name := typeNames[tc]
 	if allowedNames != nil && !allowedNames[name] {
-		err = errors.Errorf("%s not allowed here", name)
+		err = tagf(ErrUnexpectedTypeCode, "%s not allowed here", name)
 
 		return
 	}
 
 	parse, exists := knownParsers[name]
 	if !exists {
-		err = errors.Errorf("parsing %s is currently not supported", name)
+		err = tagf(ErrUnsupported, "parsing %s is currently not supported", name)
+
+		return
+	}
+
+	if err = sop.checkContext(); err != nil {
+		return
+	}
 
+	if err = sop.checkDepth(); err != nil {
 		return
 	}
 
-	return parse(sop)
+	if err = sop.checkInput(0); err != nil {
+		return
+	}
+
+	span, parent := sop.beginSpan(tc+typeMask), sop.openSpan
+	sop.openSpan = span
+
+	sop.beginIndexEntry()
+
+	// the depth of the object graph is checked by filters, class descriptors do not increase it
+	graphNode := isGraphNode(tc + typeMask)
+	if graphNode {
+		sop.objDepth++
+	}
+
+	sop.depth++
+	content, err = parse(sop)
+	sop.depth--
+
+	if graphNode {
+		sop.objDepth--
+	}
+
+	sop.endIndexEntry()
+
+	sop.openSpan = parent
+
+	if err == nil {
+		sop.typeCodes = sop.typeCodes[:len(sop.typeCodes)-1]
+		sop.endSpan(span, content)
+	}
+
+	return
+}
+
+// isGraphNode reports whether contents of type code tc are nodes of the object graph, i.e. objects, arrays and enums,
+// whose nesting is the depth seen by filters like that of ObjectInputStream.
+func isGraphNode(tc byte) bool {
+	const (
+		tcObject = 0x73
+		tcArray  = 0x75
+		tcEnum   = 0x7e
+	)
+
+	return tc == tcObject || tc == tcArray || tc == tcEnum
 }
 
 // end check has next byte in stream.
@@ -368,31 +522,44 @@ func (sop *SerializedObjectParser) end() bool {
 func (sop *SerializedObjectParser) readString(cnt int, asHex bool) (s string, err error) {
 	sop.buf.Reset()
 
-	// Prevented to allocate an extremely large block of memory.
-	if cnt > sop.maxDataBlockSize {
-		err = errors.Errorf("block data exceeds size of reader buffer. " +
-			"To increase the size, use the method SetMaxDataBlockSize or use bufio.Reader with a larger buffer size")
+	if err = sop.checkStringLength(int64(cnt)); err != nil {
+		return
+	}
 
+	if err = sop.checkInput(int64(cnt)); err != nil {
 		return
 	}
 
-	if _, err = io.CopyN(&sop.buf, sop.rd, int64(cnt)); err != nil {
+	if err = sop.alloc(int64(cnt)); err != nil {
+		return
+	}
+
+	var b []byte
+
+	if sop.zeroCopy {
+		b, err = sop.rd.take(cnt)
+	} else {
+		_, err = io.CopyN(&sop.buf, sop.rd, int64(cnt))
+		b = sop.buf.Bytes()
+	}
+
+	if err != nil {
 		err = errors.Wrap(err, "error reading string")
 
 		return
 	}
 
 	if asHex {
-		s = hex.EncodeToString(sop.buf.Bytes())
+		s = hex.EncodeToString(b)
 	} else {
-		s = sop.buf.String()
+		s = sop.bytesToString(b)
 	}
 
 	return
 }
 
 func (sop *SerializedObjectParser) readUInt8() (x uint8, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
+	if x, err = sop.rd.ReadByte(); err != nil {
 		err = errors.Wrap(err, "error reading uint8")
 	}
 
@@ -400,67 +567,91 @@ func (sop *SerializedObjectParser) readUInt8() (x uint8, err error) {
 }
 
 func (sop *SerializedObjectParser) readInt8() (x int8, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
+	var b uint8
+
+	if b, err = sop.rd.ReadByte(); err != nil {
 		err = errors.Wrap(err, "error reading int8")
 	}
 
+	return int8(b), err
+}
+
+// readScratch reads n bytes into the scratch buffer, which is overwritten by the next read.
+func (sop *SerializedObjectParser) readScratch(n int) (b []byte, err error) {
+	b = sop.scratch[:n]
+	_, err = io.ReadFull(sop.rd, b)
+
 	return
 }
 
 func (sop *SerializedObjectParser) readUInt16() (x uint16, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
-		err = errors.Wrap(err, "error reading uint16")
+	var b []byte
+
+	if b, err = sop.readScratch(2); err != nil {
+		return 0, errors.Wrap(err, "error reading uint16")
 	}
 
-	return
+	return binary.BigEndian.Uint16(b), nil
 }
 
 func (sop *SerializedObjectParser) readInt16() (x int16, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
-		err = errors.Wrap(err, "error reading int16")
+	var b []byte
+
+	if b, err = sop.readScratch(2); err != nil {
+		return 0, errors.Wrap(err, "error reading int16")
 	}
 
-	return
+	return int16(binary.BigEndian.Uint16(b)), nil
 }
 
 func (sop *SerializedObjectParser) readUInt32() (x uint32, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
-		err = errors.Wrap(err, "error reading uint32")
+	var b []byte
+
+	if b, err = sop.readScratch(4); err != nil {
+		return 0, errors.Wrap(err, "error reading uint32")
 	}
 
-	return
+	return binary.BigEndian.Uint32(b), nil
 }
 
 func (sop *SerializedObjectParser) readInt32() (x int32, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
-		err = errors.Wrap(err, "error reading int32")
+	var b []byte
+
+	if b, err = sop.readScratch(4); err != nil {
+		return 0, errors.Wrap(err, "error reading int32")
 	}
 
-	return
+	return int32(binary.BigEndian.Uint32(b)), nil
 }
 
 func (sop *SerializedObjectParser) readFloat32() (x float32, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
-		err = errors.Wrap(err, "error reading float32")
+	var b []byte
+
+	if b, err = sop.readScratch(4); err != nil {
+		return 0, errors.Wrap(err, "error reading float32")
 	}
 
-	return
+	return math.Float32frombits(binary.BigEndian.Uint32(b)), nil
 }
 
 func (sop *SerializedObjectParser) readInt64() (x int64, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
-		err = errors.Wrap(err, "error reading int64")
+	var b []byte
+
+	if b, err = sop.readScratch(8); err != nil {
+		return 0, errors.Wrap(err, "error reading int64")
 	}
 
-	return
+	return int64(binary.BigEndian.Uint64(b)), nil
 }
 
 func (sop *SerializedObjectParser) readFloat64() (x float64, err error) {
-	if err = binary.Read(sop.rd, binary.BigEndian, &x); err != nil {
-		err = errors.Wrap(err, "error reading float64")
+	var b []byte
+
+	if b, err = sop.readScratch(8); err != nil {
+		return 0, errors.Wrap(err, "error reading float64")
 	}
 
-	return
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
 }
 
 // utf reads a variable length string.
@@ -514,7 +705,7 @@ func (sop *SerializedObjectParser) magic() error {
 	magicVal, err := sop.readUInt16()
 
 	if err == nil && magicVal != 0xaced {
-		return errors.New("magic value STREAM_MAGIC not found")
+		return tagf(ErrBadMagic, "magic value STREAM_MAGIC not found")
 	}
 
 	return err
@@ -529,12 +720,31 @@ func (sop *SerializedObjectParser) version() error {
 
 	const protocolVersion = 5
 	if ver != protocolVersion {
-		return errors.Errorf("protocol version not recognized: wanted 5 got %d", ver)
+		return tagf(ErrUnsupportedVersion, "protocol version not recognized: wanted 5 got %d", ver)
 	}
 
 	return nil
 }
 
+// header reads the stream header unless it has already been read.
+func (sop *SerializedObjectParser) header() (err error) {
+	if sop.headerRead {
+		return nil
+	}
+
+	if err = sop.magic(); err != nil {
+		return
+	}
+
+	if err = sop.version(); err != nil {
+		return
+	}
+
+	sop.headerRead = true
+
+	return
+}
+
 // field contains info about a single class member.
 type field struct {
 	className string
@@ -585,24 +795,56 @@ func (sop *SerializedObjectParser) fieldDesc() (f *field, err error) {
 	return
 }
 
-// annotations reads all class annotations.
-func (sop *SerializedObjectParser) annotations(allowedNames map[string]bool) (anns []interface{}, err error) {
+// annotations reads all class annotations, consumed tells whether a post processor reads them.
+func (sop *SerializedObjectParser) annotations(
+	allowedNames map[string]bool, consumed bool,
+) (anns []interface{}, err error) {
+	var annSpans []int
+
+	if consumed {
+		// the annotations themselves are read by the post processor, the contents nested in them are not
+		procDepth := sop.procDepth
+		sop.procDepth = sop.depth + 1
+
+		defer func() { sop.procDepth = procDepth }()
+	}
+
 	for {
 		var ann interface{}
 
+		state := sop.annotationState()
+
 		if ann, err = sop.content(allowedNames); err != nil {
-			err = errors.Wrap(err, "error reading class annotation")
+			if ann != nil && sop.partial(err) {
+				anns = append(anns, ann)
+			}
 
-			return
+			if sop.partial(err) || !sop.recoverable(err) || sop.seeker == nil {
+				err = errors.Wrap(err, "error reading class annotation")
+
+				return
+			}
+
+			// in lenient mode the annotation is replaced and the rest of the annotations skipped
+			if ann, err = sop.recoverAnnotation(state, err); err != nil {
+				err = errors.Wrap(err, "error reading class annotation")
+
+				return
+			}
 		}
 
 		if _, isEndBlock := ann.(endBlockT); isEndBlock {
 			break
 		}
 
-		anns = append(anns, ann)
+		if !sop.indexing {
+			anns = append(anns, ann)
+			annSpans = sop.appendSpan(annSpans)
+		}
 	}
 
+	sop.linkElemSpans(anns, annSpans)
+
 	return
 }
 
@@ -615,10 +857,20 @@ type clazz struct {
 	name             string
 	flags            uint8
 	isEnum           bool
+	// sig is the precomputed signature of classes from a DescriptorCache.
+	sig string
+	// memo holds the *procMemo of interned classes.
+	memo *atomic.Value
 }
 
 // classDesc reads a class descriptor.
 func (sop *SerializedObjectParser) classDesc() (cls *clazz, err error) {
+	if sop.peeked != nil && sop.peeked.resumed {
+		cls, sop.peeked = sop.peeked.cls, nil
+
+		return
+	}
+
 	var x interface{}
 
 	if x, err = sop.content(allowedClazzNames); err != nil {
@@ -640,9 +892,64 @@ func (sop *SerializedObjectParser) classDesc() (cls *clazz, err error) {
 }
 
 // parseClassDesc parses a class descriptor.
-//nolint:funlen
 func parseClassDesc(sop *SerializedObjectParser) (x interface{}, err error) {
-	cls := &clazz{}
+	var (
+		cls    *clazz
+		handle int
+		entry  *descEntry
+	)
+
+	offset := sop.rd.pos - 1 // the type code has already been read
+
+	if sop.usesDescCache() {
+		if cls, handle, entry, err = sop.cachedClassDesc(offset); err != nil {
+			return
+		}
+	}
+
+	if cls == nil {
+		if cls, handle, entry, err = sop.classDescFields(offset); err != nil {
+			return
+		}
+	}
+
+	if cls.annotations, err = sop.annotations(nil, false); err != nil {
+		err = errors.Wrap(err, "error reading class annotations")
+
+		return
+	}
+
+	if cls.super, err = sop.classDesc(); err != nil {
+		err = errors.Wrap(err, "error reading class super")
+
+		return
+	}
+
+	x = sop.internClass(entry, cls, handle)
+
+	return
+}
+
+// classDescFields parses the name, serialVersionUID, flags and fields of the class descriptor starting at offset and
+// adds them to the descriptor cache if there is one. The handle is the index of the class in the handle table.
+//
+//nolint:funlen
+func (sop *SerializedObjectParser) classDescFields(offset int64) (cls *clazz, handle int, entry *descEntry,
+	err error) {
+	// nested descriptors, which are invalid as field class names anyway, are not cached
+	if cacheable := sop.usesDescCache() && !sop.rd.capturing; cacheable {
+		sop.rd.capture, sop.rd.capturing = sop.rd.capture[:0], true
+
+		defer func() {
+			sop.rd.capturing = false
+
+			if err == nil {
+				entry = sop.cacheClassDesc(cls, sop.rd.capture)
+			}
+		}()
+	}
+
+	cls = &clazz{}
 
 	if cls.name, err = sop.utf(); err != nil {
 		err = errors.Wrap(err, "error reading class name")
@@ -652,8 +959,16 @@ func parseClassDesc(sop *SerializedObjectParser) (x interface{}, err error) {
 
 	const minClassNameLength = 2
 	if len(cls.name) < minClassNameLength {
-		err = errors.Wrapf(err, "invalid class name: '%s'", cls.name)
+		err = tagf(ErrInvalidClassDesc, "invalid class name: '%s'", cls.name)
+
+		return
+	}
 
+	if sop.classHook != nil {
+		sop.classHook(cls.name, offset)
+	}
+
+	if err = sop.checkFilter(cls.name, -1); err != nil {
 		return
 	}
 
@@ -664,7 +979,11 @@ func parseClassDesc(sop *SerializedObjectParser) (x interface{}, err error) {
 		return
 	}
 
-	sop.newHandle(cls)
+	handle = len(sop.handles)
+
+	if _, err = sop.newHandle(cls); err != nil {
+		return
+	}
 
 	if cls.flags, err = sop.readUInt8(); err != nil {
 		err = errors.Wrap(err, "error reading class flags")
@@ -694,20 +1013,6 @@ func parseClassDesc(sop *SerializedObjectParser) (x interface{}, err error) {
 		cls.fields = append(cls.fields, f)
 	}
 
-	if cls.annotations, err = sop.annotations(nil); err != nil {
-		err = errors.Wrap(err, "error reading class annotations")
-
-		return
-	}
-
-	if cls.super, err = sop.classDesc(); err != nil {
-		err = errors.Wrap(err, "error reading class super")
-
-		return
-	}
-
-	x = cls
-
 	return
 }
 
@@ -718,7 +1023,7 @@ func parseClass(sop *SerializedObjectParser) (cd interface{}, err error) {
 		return
 	}
 
-	cd = sop.newHandle(cd)
+	cd, err = sop.newHandle(cd)
 
 	return
 }
@@ -732,11 +1037,24 @@ func parseReference(sop *SerializedObjectParser) (ref interface{}, err error) {
 		return
 	}
 
+	sop.refs++
+
 	const refIDMask = 0x7e0000
 	i := int(refIdx - refIDMask)
 
-	if i > -1 && i < len(sop.handles) {
-		ref = sop.handles[i]
+	switch {
+	case i >= sop.handleBase && !sop.knownHandle(i-sop.handleBase):
+		// the handles assigned in the part of the stream skipped by resynchronizing are unknown
+	case i > -1 && i < sop.handleBase && sop.resolveHandle != nil:
+		// the handle precedes the part of the stream being read
+		if ref, err = sop.resolveHandle(i); err != nil {
+			return
+		}
+
+		sop.spanHandle(i)
+	case i >= sop.handleBase && i-sop.handleBase < len(sop.handles):
+		ref = sop.handles[i-sop.handleBase]
+		sop.spanHandle(i)
 	}
 
 	return
@@ -755,7 +1073,9 @@ func parseArray(sop *SerializedObjectParser) (arr interface{}, err error) {
 		"class": cls,
 	}
 
-	sop.newHandle(res)
+	if _, err = sop.newHandle(res); err != nil {
+		return
+	}
 
 	var size int32
 
@@ -771,42 +1091,135 @@ func parseArray(sop *SerializedObjectParser) (arr interface{}, err error) {
 		return
 	}
 
+	if err = sop.checkFilter(cls.name, int64(size)); err != nil {
+		return
+	}
+
+	// byte arrays passed to a sink are not buffered, so their length is not limited
+	if cls.name == "[B" && size >= 0 && sop.sinks(int64(size)) {
+		sop.enterClass(cls)
+		arr, err = sop.toSink(int64(size))
+		sop.leaveClass(cls)
+
+		return
+	}
+
+	if err = sop.checkArrayLength(size); err != nil {
+		return
+	}
+
 	primHandler, exists := primitiveHandlers[string(cls.name[1])]
 	if !exists {
-		err = errors.Errorf("unknown field type '%s'", string(cls.name[1]))
+		err = tagf(ErrInvalidClassDesc, "unknown field type '%s'", string(cls.name[1]))
 
 		return
 	}
 
-	var array []interface{}
+	// primitive elements are decoded in bulk, every other element takes at least one byte of input
+	width, isPrimitive := primitiveSizes[cls.name[1]]
+	inputSize, allocSize := int64(size), int64(size)*interfaceSize
+
+	if isPrimitive {
+		inputSize = int64(size) * int64(width)
+		allocSize = inputSize
+	}
+
+	if err = sop.checkInput(inputSize); err != nil {
+		return
+	}
+
+	if err = sop.alloc(allocSize); err != nil {
+		return
+	}
+
+	sop.enterClass(cls)
+	sop.path = append(sop.path, pathSeg{})
+
+	if isPrimitive {
+		if arr, err = sop.primitiveArray(cls.name[1], width, int(size)); err == nil {
+			sop.path = sop.path[:len(sop.path)-1]
+			sop.leaveClass(cls)
+		}
+
+		return
+	}
+
+	var (
+		array     []interface{}
+		elemSpans []int
+	)
 
 	for i := 0; i < int(size); i++ {
 		var nxt interface{}
 
+		if err = sop.checkContext(); err != nil {
+			return
+		}
+
+		sop.path[len(sop.path)-1].index = i
+
 		if nxt, err = primHandler(sop); err != nil {
+			if sop.partial(err) {
+				if nxt != nil {
+					array = append(array, nxt)
+				}
+
+				arr = &PartialArray{Elements: array, Length: int(size)}
+			}
+
 			err = errors.Wrap(err, "error reading primitive array member")
 
 			return
 		}
 
-		array = append(array, nxt)
+		if !sop.indexing {
+			array = append(array, nxt)
+			elemSpans = sop.appendSpan(elemSpans)
+		}
 	}
 
+	sop.path = sop.path[:len(sop.path)-1]
+	sop.leaveClass(cls)
+
+	sop.linkElemSpans(array, elemSpans)
 	arr = array
 
 	return
 }
 
+// enterClass adds the class of the object or array being read to the path. The path is not removed when an error
+// occurs so it leads to the value which failed.
+func (sop *SerializedObjectParser) enterClass(cls *clazz) {
+	if cls != nil {
+		sop.path = append(sop.path, pathSeg{class: cls.name})
+		sop.spanClass(cls.name)
+	}
+}
+
+// leaveClass removes the class added by enterClass.
+func (sop *SerializedObjectParser) leaveClass(cls *clazz) {
+	if cls != nil {
+		sop.path = sop.path[:len(sop.path)-1]
+	}
+}
+
 // newDeferredHandle reserves an object handle slot and returns a func which can set the slot value at a later time.
-func (sop *SerializedObjectParser) newDeferredHandle() func(interface{}) interface{} {
+func (sop *SerializedObjectParser) newDeferredHandle() (func(interface{}) interface{}, error) {
+	if err := sop.checkHandles(); err != nil {
+		return nil, err
+	}
+
 	idx := len(sop.handles)
+	sop.spanHandle(sop.handleBase + idx)
+	sop.indexHandle()
 	sop.handles = append(sop.handles, nil)
+	sop.refs++
 
 	return func(obj interface{}) interface{} {
-		sop.handles[idx] = obj
+		sop.handles[idx] = sop.retained(obj)
 
 		return obj
-	}
+	}, nil
 }
 
 func parseEnum(sop *SerializedObjectParser) (enum interface{}, err error) {
@@ -818,7 +1231,11 @@ func parseEnum(sop *SerializedObjectParser) (enum interface{}, err error) {
 		return
 	}
 
-	deferredHandle := sop.newDeferredHandle()
+	var deferredHandle func(interface{}) interface{}
+
+	if deferredHandle, err = sop.newDeferredHandle(); err != nil {
+		return
+	}
 
 	var enumConstant interface{}
 
@@ -847,9 +1264,21 @@ func parseBlockData(sop *SerializedObjectParser) (bd interface{}, err error) {
 		return
 	}
 
-	data := make([]byte, size)
+	if sop.sinks(int64(size)) {
+		return sop.toSink(int64(size))
+	}
 
-	if _, err = io.ReadFull(sop.rd, data); err == nil {
+	if err = sop.checkBlockDataSize(int64(size)); err != nil {
+		return
+	}
+
+	if err = sop.alloc(int64(size)); err != nil {
+		return
+	}
+
+	var data []byte
+
+	if data, err = sop.readBytes(int(size)); err == nil {
 		bd = data
 	}
 
@@ -865,17 +1294,25 @@ func parseBlockDataLong(sop *SerializedObjectParser) (bdl interface{}, err error
 		return
 	}
 
-	// Prevented to allocate an extremely large block of memory.
-	if int(size) > sop.maxDataBlockSize {
-		err = errors.Errorf("block data exceeds size of reader buffer. " +
-			"To increase the size, use the method SetMaxDataBlockSize or use bufio.Reader with a larger buffer size")
+	if sop.sinks(int64(size)) {
+		return sop.toSink(int64(size))
+	}
+
+	if err = sop.checkBlockDataSize(int64(size)); err != nil {
+		return
+	}
+
+	if err = sop.checkInput(int64(size)); err != nil {
+		return
+	}
 
+	if err = sop.alloc(int64(size)); err != nil {
 		return
 	}
 
-	data := make([]byte, size)
+	var data []byte
 
-	if _, err = io.ReadFull(sop.rd, data); err == nil {
+	if data, err = sop.readBytes(int(size)); err == nil {
 		bdl = data
 
 		return
@@ -888,7 +1325,7 @@ func parseString(sop *SerializedObjectParser) (str interface{}, err error) {
 	if str, err = sop.utf(); err != nil {
 		err = errors.Wrap(err, "error parsing string")
 	} else {
-		str = sop.newHandle(str)
+		str, err = sop.newHandle(str)
 	}
 
 	return
@@ -898,7 +1335,7 @@ func parseLongString(sop *SerializedObjectParser) (longStr interface{}, err erro
 	if longStr, err = sop.utfLong(); err != nil {
 		err = errors.Wrap(err, "error parsing long string")
 	} else {
-		sop.newHandle(longStr)
+		longStr, err = sop.newHandle(longStr)
 	}
 
 	return
@@ -923,25 +1360,57 @@ func (sop *SerializedObjectParser) values(cls *clazz) (vals map[string]interface
 	var handler primitiveHandler
 
 	vals = make(map[string]interface{})
+	seg := len(sop.path)
+	sop.path = append(sop.path, pathSeg{})
 
 	for _, field := range cls.fields {
 		if field == nil {
 			continue
 		}
 
+		sop.path[seg].field = field.name
+
 		if handler, exists = primitiveHandlers[field.typeName]; !exists {
-			err = errors.Errorf("unknown field type '%s'", field.typeName)
+			err = tagf(ErrInvalidClassDesc, "unknown field type '%s'", field.typeName)
 
 			return
 		}
 
-		if vals[field.name], err = handler(sop); err != nil {
+		if err = sop.alloc(mapEntrySize); err != nil {
+			return
+		}
+
+		if err = sop.checkContext(); err != nil {
+			return
+		}
+
+		var val interface{}
+
+		if val, err = handler(sop); err != nil {
+			if sop.partial(err) {
+				vals[incompleteFieldKey] = field.name
+
+				if isObjectType(field.typeName) && val != nil {
+					vals[field.name] = val
+				}
+			}
+
 			err = errors.Wrap(err, "error reading primitive field value")
 
 			return
 		}
+
+		if !sop.indexing {
+			vals[field.name] = val
+
+			if isObjectType(field.typeName) {
+				sop.linkFieldSpan(vals, field.name)
+			}
+		}
 	}
 
+	sop.path = sop.path[:seg]
+
 	return
 }
 
@@ -955,9 +1424,20 @@ func (sop *SerializedObjectParser) annotationsAsMap(cls *clazz, isBlock bool) (d
 		return
 	}
 
-	var anns []interface{}
+	var (
+		anns     []interface{}
+		postproc PostProc
+	)
+
+	if !isBlock {
+		postproc = sop.postProc(cls)
+	}
+
+	if anns, err = sop.annotations(nil, postproc != nil); err != nil {
+		if sop.partial(err) {
+			data["@"] = anns
+		}
 
-	if anns, err = sop.annotations(nil); err != nil {
 		err = errors.Wrap(err, "error reading annotations")
 
 		return
@@ -965,15 +1445,33 @@ func (sop *SerializedObjectParser) annotationsAsMap(cls *clazz, isBlock bool) (d
 
 	data["@"] = anns
 
-	if !isBlock {
-		if postproc, exists := KnownPostProcs[cls.name+"@"+cls.serialVersionUID]; exists {
-			data, err = postproc(data, anns)
+	if postproc != nil {
+		var processed map[string]interface{}
+
+		if processed, err = postproc(newBlockDataReader(data, anns)); err == nil {
+			data = processed
+		} else if sop.lenient {
+			// the annotations have been read completely so the rest of the object can still be read
+			sop.softErr, err = sop.warn(err), nil
 		}
 	}
 
 	return
 }
 
+// postProc returns the post processor registered for cls, if any.
+func (sop *SerializedObjectParser) postProc(cls *clazz) PostProc {
+	if sop.indexing {
+		return nil
+	}
+
+	if sop.postProcs != nil {
+		return sop.postProcs.lookup(cls)
+	}
+
+	return KnownPostProcs[cls.signature()]
+}
+
 // classData reads a serialized class into a generic data structure.
 func (sop *SerializedObjectParser) classData(cls *clazz) (data map[string]interface{}, err error) {
 	if cls == nil {
@@ -995,13 +1493,13 @@ func (sop *SerializedObjectParser) classData(cls *clazz) (data map[string]interf
 		return sop.annotationsAsMap(cls, false)
 
 	case ScExternalizeWithBlockData: // SC_EXTERNALIZABLE without SC_BLOCKDATA
-		return nil, errors.New("unable to parse version 1 external content")
+		return nil, tagf(ErrUnsupported, "unable to parse version 1 external content")
 
 	case ScExternalizeWithoutBlockData: // SC_EXTERNALIZABLE with SC_BLOCKDATA
 		return sop.annotationsAsMap(cls, true)
 
 	default:
-		return nil, errors.Errorf("unable to deserialize class with flags %#x", cls.flags)
+		return nil, tagf(ErrInvalidClassDesc, "unable to deserialize class with flags %#x", cls.flags)
 	}
 }
 
@@ -1027,7 +1525,7 @@ func (sop *SerializedObjectParser) recursiveClassData(cls *clazz, obj map[string
 	}
 
 	fields, err := sop.classData(cls)
-	if err != nil {
+	if err != nil && !sop.partial(err) {
 		return errors.Wrap(err, "error reading recursive class data")
 	}
 
@@ -1037,12 +1535,16 @@ func (sop *SerializedObjectParser) recursiveClassData(cls *clazz, obj map[string
 		obj[name] = val
 	}
 
-	return nil
+	sop.copyFieldSpans(fields, obj)
+
+	return errors.Wrap(err, "error reading recursive class data")
 }
 
 func parseObject(sop *SerializedObjectParser) (obj interface{}, err error) {
 	var cls *clazz
 
+	offset := sop.contentOffset
+
 	if cls, err = sop.classDesc(); err != nil {
 		err = errors.Wrap(err, "error reading object class")
 
@@ -1054,178 +1556,161 @@ func parseObject(sop *SerializedObjectParser) (obj interface{}, err error) {
 		"extends": make(map[string]interface{}),
 	}
 
-	deferredHandle := sop.newDeferredHandle()
-
-	seen := map[*clazz]bool{}
-	if err = sop.recursiveClassData(cls, objMap, seen); err != nil {
-		err = errors.Wrap(err, "error reading recursive class data")
+	var deferredHandle func(interface{}) interface{}
 
+	if deferredHandle, err = sop.newDeferredHandle(); err != nil {
 		return
 	}
 
-	obj = deferredHandle(objMap)
-
-	return
-}
-
-// postProcSize reads the object size as an int32 from the first data element.
-func postProcSize(data []interface{}, offset int) (size int, err error) {
-	if len(data) < 1 {
-		err = errors.New("invalid data: at least one element required")
-
-		return
-	}
+	sop.enterClass(cls)
 
-	b, isByteSlice := data[0].([]byte)
-	if !isByteSlice {
-		err = errors.New("unexpected data at position 0")
+	// softErr is set when a post processor of the object's classes fails in lenient mode
+	softErr := sop.softErr
+	sop.softErr = nil
 
-		return
-	}
+	seen := map[*clazz]bool{}
+	if err = sop.recursiveClassData(cls, objMap, seen); err != nil {
+		if sop.partial(err) {
+			objMap[incompleteKey] = true
+			obj = deferredHandle(objMap)
+		}
 
-	const minLength = 4
-	if len(b) < offset+minLength {
-		err = errors.Errorf("incorrect data at position 0: wanted at least %d bytes, got %d", offset+minLength, len(b))
+		err = errors.Wrap(err, "error reading recursive class data")
 
 		return
 	}
 
-	var size32 int32
-	if err = binary.Read(bytes.NewReader(b[offset:]), binary.BigEndian, &size32); err != nil {
-		err = errors.Wrap(err, "error reading size")
+	sop.leaveClass(cls)
 
-		return
+	if sop.softErr != nil {
+		obj = deferredHandle(&Undecodable{Class: cls.name, Offset: offset, Err: sop.softErr})
+	} else {
+		obj = deferredHandle(objMap)
 	}
 
-	size = int(size32)
+	sop.softErr = softErr
 
 	return
 }
 
 // listPostProc populates the object value with a []interface{}.
-func listPostProc(fields map[string]interface{}, data []interface{}) (map[string]interface{}, error) {
-	size, err := postProcSize(data, 0)
+func listPostProc(r *BlockDataReader) (map[string]interface{}, error) {
+	size, err := r.ReadInt()
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "error reading size")
 	}
 
-	if len(data) != size+1 {
-		return nil, errors.Errorf("incorrect number of elements: want %d got %d", size, len(data)-1)
+	list, err := r.readObjects(size)
+	if err != nil {
+		return nil, err
 	}
 
-	if size > 1 {
-		fields["value"] = data[1:size]
-	} else {
-		fields["value"] = make([]interface{}, 0)
-	}
+	fields := r.DefaultFields()
+	fields["value"] = list
 
-	return fields, err
+	return fields, nil
 }
 
 // mapPostProc populates the object value with a map of key/value pairs.
-func mapPostProc(fields map[string]interface{}, data []interface{}) (map[string]interface{}, error) {
-	size, err := postProcSize(data, 4)
+func mapPostProc(r *BlockDataReader) (map[string]interface{}, error) {
+	if _, err := r.ReadInt(); err != nil {
+		return nil, errors.Wrap(err, "error reading capacity")
+	}
+
+	size, err := r.ReadInt()
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "error reading size")
 	}
 
-	if size*2+1 > len(data) {
-		return nil, errors.Errorf("incorrect number of elements: want %d got %d", size, len(data)-1)
+	pairs, err := r.readPairs(size)
+	if err != nil {
+		return nil, err
 	}
 
 	m := make(map[string]interface{})
 
-	for i := 0; i < size; i++ {
-		key := data[2*i+1]
-		value := data[2*i+2]
-
-		if s, isString := key.(string); isString {
-			m[s] = value
+	for i := 0; i < len(pairs); i += 2 {
+		if s, isString := pairs[i].(string); isString {
+			m[s] = pairs[i+1]
 		}
 	}
 
+	fields := r.DefaultFields()
 	fields["value"] = m
 
 	return fields, nil
 }
 
 // enumMapPostProc populates the object value with a map of key/value pairs where keys are enum constants.
-func enumMapPostProc(fields map[string]interface{}, data []interface{}) (map[string]interface{}, error) {
-	size, err := postProcSize(data, 0)
+func enumMapPostProc(r *BlockDataReader) (map[string]interface{}, error) {
+	size, err := r.ReadInt()
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "error reading size")
 	}
 
-	if size*2+1 > len(data) {
-		return nil, errors.Errorf("incorrect number of elements: want %d got %d", size, len(data)-1)
+	pairs, err := r.readPairs(size)
+	if err != nil {
+		return nil, err
 	}
 
 	m := make(map[string]interface{})
 
-	for i := 0; i < size; i++ {
-		key := data[2*i+1]
-		value := data[2*i+2]
-
-		if mk, isMap := key.(map[string]interface{}); isMap {
+	for i := 0; i < len(pairs); i += 2 {
+		if mk, isMap := pairs[i].(map[string]interface{}); isMap {
 			if s, isString := mk["value"].(string); isString {
-				m[s] = value
+				m[s] = pairs[i+1]
 			}
 		}
 	}
 
+	fields := r.DefaultFields()
 	fields["value"] = m
 
 	return fields, nil
 }
 
 // hashSetPostProc populates the object value with a map of key/value pairs.
-func hashSetPostProc(fields map[string]interface{}, data []interface{}) (map[string]interface{}, error) {
-	size, err := postProcSize(data, 8)
+func hashSetPostProc(r *BlockDataReader) (map[string]interface{}, error) {
+	if _, err := r.ReadInt(); err != nil {
+		return nil, errors.Wrap(err, "error reading capacity")
+	}
+
+	if _, err := r.ReadFloat(); err != nil {
+		return nil, errors.Wrap(err, "error reading load factor")
+	}
+
+	size, err := r.ReadInt()
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "error reading size")
 	}
 
-	if len(data) != size+1 {
-		return nil, errors.Errorf("incorrect number of elements: want %d got %d", size, len(data)-1)
+	keys, err := r.readObjects(size)
+	if err != nil {
+		return nil, err
 	}
 
 	m := make(map[string]bool)
 
-	if size > 1 {
-		for idx := range data[1:size] {
-			key := data[idx+1]
-			if s, isString := key.(string); isString {
-				m[s] = true
-			}
+	for _, key := range keys {
+		if s, isString := key.(string); isString {
+			m[s] = true
 		}
 	}
 
+	fields := r.DefaultFields()
 	fields["value"] = m
 
 	return fields, nil
 }
 
 // datePostProc populates the object value with a time.Time.
-func datePostProc(fields map[string]interface{}, data []interface{}) (map[string]interface{}, error) {
-	if len(data) < 1 {
-		return nil, errors.New("invalid data: at least one element required")
-	}
-
-	b, isByteSlice := data[0].([]byte)
-	if !isByteSlice {
-		return nil, errors.New("unexpected data at position 0")
-	}
-
-	const timestampBlockSize = 8
-	if len(b) < timestampBlockSize {
-		return nil, errors.Errorf("incorrect data at position 0: wanted 8 bytes, got %d", len(b))
-	}
-
-	var timestamp int64
-	if err := binary.Read(bytes.NewReader(b[0:timestampBlockSize]), binary.BigEndian, &timestamp); err != nil {
+func datePostProc(r *BlockDataReader) (map[string]interface{}, error) {
+	timestamp, err := r.ReadLong()
+	if err != nil {
 		return nil, errors.Wrap(err, "error reading timestamp")
 	}
 
+	fields := r.DefaultFields()
 	fields["value"] = time.Unix(0, timestamp*int64(time.Millisecond))
 
 	return fields, nil
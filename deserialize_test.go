@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -119,7 +120,7 @@ func TestStringPrematureEnd(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), expectedErrStr) {
 		t.Fail()
 	}
-	expectedErrStr = "block data exceeds size"
+	// the length exceeds the input rather than a limit
 	err = getErr(streamMagic + streamVersion + tcString + "00" + strValAsHex)
 	if err == nil || !strings.Contains(err.Error(), expectedErrStr) {
 		t.Fail()
@@ -378,19 +379,13 @@ func TestDeserializePrimitiveArray(t *testing.T) {
 	if err != nil || len(obj) != 3 {
 		t.Fail()
 	}
-	arr, isArray := obj[1].([]interface{})
+	arr, isArray := obj[1].([]int32)
 	if !isArray {
-		t.Fail()
-	}
-	expected := []interface{}{
-		int32(12),
-		int32(34),
-		int32(56),
+		t.Fatalf("unexpected array type %T", obj[1])
 	}
-	for idx, v := range expected {
-		if arr[idx] != v {
-			t.Fail()
-		}
+	expected := []int32{12, 34, 56}
+	if !reflect.DeepEqual(arr, expected) {
+		t.Fail()
 	}
 }
 
@@ -424,8 +419,8 @@ func TestDeserializeArrayFields(t *testing.T) {
 		t.Fail()
 	}
 	expected := map[string]interface{}{
-		"ia":  []interface{}{int32(12), int32(34), int32(56)},
-		"iaa": []interface{}{[]interface{}{int32(11), int32(12)}, []interface{}{int32(21), int32(22), int32(23)}},
+		"ia":  []int32{12, 34, 56},
+		"iaa": []interface{}{[]int32{11, 12}, []int32{21, 22, 23}},
 		"sa":  []interface{}{"foo", "bar"},
 	}
 	for k, v := range expected {
@@ -624,7 +619,7 @@ func TestDeserializeArrayList(t *testing.T) {
 	if err != nil || len(obj) != 3 {
 		t.Fail()
 	}
-	expected := []interface{}{"foo"}
+	expected := []interface{}{"foo", int32(123)}
 	if !reflect.DeepEqual(obj[1], expected) {
 		t.Fail()
 	}
@@ -635,7 +630,7 @@ func TestDeserializeArrayDeque(t *testing.T) {
 	if err != nil || len(obj) != 3 {
 		t.Fail()
 	}
-	expected := []interface{}{"foo"}
+	expected := []interface{}{"foo", int32(123)}
 	if !reflect.DeepEqual(obj[1], expected) {
 		t.Fail()
 	}
@@ -663,3 +658,58 @@ func TestDeserializeDate(t *testing.T) {
 		t.Fail()
 	}
 }
+
+// -------------------------------- //
+// --------- Benchmarks ----------- //
+// -------------------------------- //
+
+func BenchmarkParseSerializedObject(b *testing.B) {
+	names := make([]string, 0, len(objs))
+	for name := range objs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buf := objs[name]
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(buf)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseSerializedObject(buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkReadPrimitives(b *testing.B) {
+	// a uint8, uint16, int32, int64 and float64 take 23 bytes
+	const size, count = 23, 256
+
+	buf := make([]byte, size*count)
+	rd := bytes.NewReader(buf)
+	sop := NewSerializedObjectParser(rd)
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if i%count == 0 {
+			rd.Reset(buf)
+			sop.rd.br.Reset(rd)
+		}
+
+		_, err1 := sop.readUInt8()
+		_, err2 := sop.readUInt16()
+		_, err3 := sop.readInt32()
+		_, err4 := sop.readInt64()
+		_, err5 := sop.readFloat64()
+
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil {
+			b.Fatal("unexpected error")
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package jserial
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Errors identifying why a stream could not be parsed, use errors.Is to test for them.
+var (
+	ErrBadMagic           = errors.New("bad stream magic")
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+	ErrUnknownTypeCode    = errors.New("unknown type code")
+	ErrUnexpectedTypeCode = errors.New("unexpected type code")
+	ErrUnsupported        = errors.New("unsupported content")
+	ErrInvalidClassDesc   = errors.New("invalid class descriptor")
+	ErrTruncated          = errors.New("premature end of input")
+)
+
+// taggedError is an error with its own message which is identified by a sentinel error.
+type taggedError struct {
+	msg      string
+	sentinel error
+}
+
+func (e *taggedError) Error() string {
+	return e.msg
+}
+
+func (e *taggedError) Unwrap() error {
+	return e.sentinel
+}
+
+// tagf returns an error with a stack trace and the formatted message which is identified by sentinel.
+func tagf(sentinel error, format string, args ...interface{}) error {
+	return errors.WithStack(&taggedError{msg: fmt.Sprintf(format, args...), sentinel: sentinel})
+}
+
+// ParseError is returned when a stream cannot be parsed, it describes where the error occurred.
+type ParseError struct {
+	// Offset is the position in the stream at which the error was detected.
+	Offset int64
+	// TypeCode is the type code (e.g. 0x73 for TC_OBJECT) of the innermost content being read, or zero if the error
+	// occurred outside of any content, e.g. while reading the stream header.
+	TypeCode byte
+	// Path leads from the outermost object to the field being read, e.g. `com.acme.Order.items[3].price`.
+	Path string
+	// Window holds the bytes surrounding Offset, starting at WindowOffset.
+	Window       []byte
+	WindowOffset int64
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return e.location() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Format prints the window and the stack trace of the underlying error with %+v.
+func (e *ParseError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\n%s\n%+v", e.location(), e.Hex(), e.Err)
+
+		return
+	}
+
+	io.WriteString(s, e.Error()) //nolint:errcheck
+}
+
+// Hex returns a hex dump of Window with the position of the error marked by `|`.
+func (e *ParseError) Hex() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%08x:", e.WindowOffset)
+
+	for i := 0; i <= len(e.Window); i++ {
+		if e.WindowOffset+int64(i) == e.Offset {
+			sb.WriteString(" |")
+		}
+
+		if i < len(e.Window) {
+			sb.WriteString(" " + hex.EncodeToString(e.Window[i:i+1]))
+		}
+	}
+
+	return sb.String()
+}
+
+func (e *ParseError) location() string {
+	loc := fmt.Sprintf("parse error at offset %d", e.Offset)
+
+	if e.TypeCode != 0 {
+		name := "unknown"
+		if tc := e.TypeCode - typeMask; tc <= typeNameMax {
+			name = typeNames[tc]
+		}
+
+		loc += fmt.Sprintf(" in %s (%#x)", name, e.TypeCode)
+	}
+
+	if e.Path != "" {
+		loc += " at " + e.Path
+	}
+
+	return loc
+}
+
+// pathSeg is an element of the path to the value being read: the class of an object or array, a field name or an
+// array index.
+type pathSeg struct {
+	class string
+	field string
+	index int
+}
+
+// lookahead is the maximum number of bytes following the error included in the window of a ParseError.
+const lookahead = 16
+
+// parseError returns err as a *ParseError describing the current position of the parser. Running out of input is
+// reported as ErrTruncated.
+func (sop *SerializedObjectParser) parseError(err error) error {
+	var parseErr *ParseError
+	if err == nil || errors.As(err, &parseErr) {
+		return err
+	}
+
+	if sop.rd.eof {
+		err = tagf(ErrTruncated, "premature end of input")
+	}
+
+	parseErr = &ParseError{
+		Offset: sop.rd.pos,
+		Path:   sop.pathString(),
+		Err:    err,
+	}
+
+	if len(sop.typeCodes) > 0 {
+		parseErr.TypeCode = sop.typeCodes[len(sop.typeCodes)-1]
+	}
+
+	parseErr.Window, parseErr.WindowOffset = sop.rd.history()
+
+	n := sop.rd.Buffered()
+	if n > lookahead {
+		n = lookahead
+	}
+
+	if ahead, peekErr := sop.rd.Peek(n); peekErr == nil {
+		parseErr.Window = append(parseErr.Window, ahead...)
+	}
+
+	return parseErr
+}
+
+// resetPath clears the path and type codes left by a previous error.
+func (sop *SerializedObjectParser) resetPath() {
+	sop.path = sop.path[:0]
+	sop.typeCodes = sop.typeCodes[:0]
+}
+
+// pathString formats the path to the value being read.
+func (sop *SerializedObjectParser) pathString() string {
+	var sb strings.Builder
+
+	for _, seg := range sop.path {
+		switch {
+		case seg.class != "":
+			// only the outermost class is included, the others follow from the field names
+			if sb.Len() == 0 {
+				sb.WriteString(seg.class)
+			}
+		case seg.field != "":
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+
+			sb.WriteString(seg.field)
+		default:
+			fmt.Fprintf(&sb, "[%d]", seg.index)
+		}
+	}
+
+	return sb.String()
+}
+
+// classPath returns the classes of the objects and arrays being read.
+func (sop *SerializedObjectParser) classPath() (classes []string) {
+	for _, seg := range sop.path {
+		if seg.class != "" {
+			classes = append(classes, seg.class)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,71 @@
+package jserial
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func parseErrorOf(t *testing.T, hexStr string) *ParseError {
+	var parseErr *ParseError
+	if err := getErr(hexStr); !errors.As(err, &parseErr) {
+		t.Fatalf("expected parse error, got %+v", err)
+	}
+	return parseErr
+}
+
+func TestParseErrorSentinels(t *testing.T) {
+	for _, tc := range []struct {
+		hexStr   string
+		sentinel error
+	}{
+		{"acde0005", ErrBadMagic},
+		{"aced0004", ErrUnsupportedVersion},
+		{"ac", ErrTruncated},
+		{streamMagic + streamVersion + "67", ErrUnknownTypeCode},
+		{streamMagic + streamVersion + tcString + "0008" + strValAsHex, ErrTruncated},
+		{streamMagic + streamVersion + tcReset, ErrUnsupported},
+		{streamHex("flags", "00"), ErrInvalidClassDesc},
+		{streamHex("fieldType", "Q"), ErrInvalidClassDesc},
+		{streamHex("classDesc", tcObject), ErrUnexpectedTypeCode},
+		{streamPrefix + tcClassDesc + encodeStr("X") + serialVer, ErrInvalidClassDesc},
+	} {
+		if parseErr := parseErrorOf(t, tc.hexStr); !errors.Is(parseErr, tc.sentinel) {
+			t.Errorf("%s: expected %v, got %+v", tc.hexStr, tc.sentinel, parseErr)
+		}
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	parseErr := parseErrorOf(t, streamMagic+streamVersion+tcString+encodeStr("foo")+"67")
+	if parseErr.Offset != 10 || parseErr.TypeCode != 0x67 || parseErr.Path != "" {
+		t.Errorf("unexpected parse error %v", parseErr)
+	}
+	if hexStr := parseErr.Hex(); hexStr != "00000000: ac ed 00 05 74 00 03 66 6f 6f | 67" {
+		t.Errorf("unexpected hex %s", hexStr)
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", parseErr), "| 67") {
+		t.Errorf("expected hex in %+v", parseErr)
+	}
+}
+
+func TestParseErrorPath(t *testing.T) {
+	item := encodeStr("com.acme.Item")
+	itemArray := encodeStr("[Lcom.acme.Item;")
+	hexStr := streamPrefix + tcClassDesc + encodeStr("com.acme.Order") + serialVer + scSerializable + "0001" +
+		hex.EncodeToString([]byte("[")) + encodeStr("items") + tcString + itemArray + tcEndBlockData + tcNull +
+		tcArray + tcClassDesc + itemArray + serialVer + scSerializable + "0000" + tcEndBlockData + tcNull + "00000002" +
+		tcObject + tcClassDesc + item + serialVer + scSerializable + "0001" + hex.EncodeToString([]byte("I")) +
+		encodeStr("price") + tcEndBlockData + tcNull + "0000002a" +
+		tcObject + tcReference + "007e0005" + "0000"
+
+	parseErr := parseErrorOf(t, hexStr)
+	if parseErr.Path != "com.acme.Order.items[1].price" || parseErr.TypeCode != 0x73 {
+		t.Errorf("unexpected parse error %v", parseErr)
+	}
+	if !errors.Is(parseErr, ErrTruncated) || parseErr.Offset != int64(len(hexStr)/2) {
+		t.Errorf("unexpected parse error %v", parseErr)
+	}
+}
@@ -0,0 +1,286 @@
+package jserial
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SerialFilter accepts or rejects the classes found in a stream and limits the size of the stream, using the same
+// pattern syntax as the jdk.serialFilter system property, e.g.
+//
+//	maxdepth=10;maxrefs=1000;maxbytes=65536;maxarray=1024;com.example.*;java.base/*;!*
+//
+// Patterns are checked in order and the first one matching a class decides whether it is accepted (`pattern`) or
+// rejected (`!pattern`). Classes not matched by any pattern are accepted. A pattern is either an exact class name,
+// `pkg.*` (all classes in a package), `pkg.**` (all classes in a package and its sub packages), `prefix*` or `*`.
+// Patterns may be prefixed with `module/`; since serialized streams do not carry module information, module names
+// are resolved for well known JDK packages only. Like ObjectInputStream, maxdepth limits the nesting of objects,
+// arrays and enums, top level contents being at depth 1.
+// see: https://docs.oracle.com/en/java/javase/17/core/serialization-filtering1.html
+type SerialFilter struct {
+	pattern  string
+	rules    []filterRule
+	maxDepth int64
+	maxRefs  int64
+	maxBytes int64
+	maxArray int64
+}
+
+// filterRule is a single class pattern of a SerialFilter.
+type filterRule struct {
+	text   string
+	reject bool
+	module string
+	match  func(name string) bool
+}
+
+// FilterError is returned when a stream is rejected by a SerialFilter.
+type FilterError struct {
+	// Class is the name of the rejected class.
+	Class string
+	// Rule is the pattern or limit (e.g. `maxarray=1024`) which rejected the class.
+	Rule string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("class '%s' rejected by serial filter rule '%s'", e.Class, e.Rule)
+}
+
+// ParseSerialFilter parses a filter in jdk.serialFilter syntax.
+func ParseSerialFilter(pattern string) (*SerialFilter, error) {
+	filter := &SerialFilter{
+		pattern:  pattern,
+		maxDepth: -1,
+		maxRefs:  -1,
+		maxBytes: -1,
+		maxArray: -1,
+	}
+
+	for _, part := range strings.Split(pattern, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if idx := strings.Index(part, "="); idx > -1 {
+			if err := filter.parseLimit(part[:idx], part[idx+1:]); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		rule, err := parseFilterRule(part)
+		if err != nil {
+			return nil, err
+		}
+
+		filter.rules = append(filter.rules, rule)
+	}
+
+	return filter, nil
+}
+
+// WithSerialFilter rejects streams containing classes or exceeding limits not allowed by filter. The filter is
+// checked as each class descriptor and array is read, before any object data is decoded.
+func WithSerialFilter(filter *SerialFilter) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.filter = filter
+	}
+}
+
+// String returns the pattern the filter was parsed from.
+func (f *SerialFilter) String() string {
+	return f.pattern
+}
+
+func (f *SerialFilter) parseLimit(name, value string) error {
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit < 0 {
+		return errors.Errorf("invalid serial filter limit value '%s=%s'", name, value)
+	}
+
+	switch name {
+	case "maxdepth":
+		f.maxDepth = limit
+	case "maxrefs":
+		f.maxRefs = limit
+	case "maxbytes":
+		f.maxBytes = limit
+	case "maxarray":
+		f.maxArray = limit
+	default:
+		return errors.Errorf("unknown serial filter limit '%s'", name)
+	}
+
+	return nil
+}
+
+func parseFilterRule(text string) (rule filterRule, err error) {
+	rule.text = text
+	pattern := text
+
+	if strings.HasPrefix(pattern, "!") {
+		rule.reject = true
+		pattern = pattern[1:]
+	}
+
+	if idx := strings.Index(pattern, "/"); idx > -1 {
+		rule.module = pattern[:idx]
+		pattern = pattern[idx+1:]
+
+		if rule.module == "" {
+			err = errors.Errorf("invalid serial filter pattern '%s': empty module name", text)
+
+			return
+		}
+	}
+
+	switch {
+	case pattern == "":
+		err = errors.Errorf("invalid serial filter pattern '%s'", text)
+	case pattern == "*":
+		rule.match = func(string) bool { return true }
+	case strings.HasSuffix(pattern, ".**"):
+		pkg := pattern[:len(pattern)-2]
+		rule.match = func(name string) bool {
+			return strings.HasPrefix(name, pkg)
+		}
+	case strings.HasSuffix(pattern, ".*"):
+		pkg := pattern[:len(pattern)-1]
+		rule.match = func(name string) bool {
+			return strings.HasPrefix(name, pkg) && !strings.Contains(name[len(pkg):], ".")
+		}
+	case strings.HasSuffix(pattern, "*"):
+		prefix := pattern[:len(pattern)-1]
+		rule.match = func(name string) bool {
+			return strings.HasPrefix(name, prefix)
+		}
+	default:
+		rule.match = func(name string) bool {
+			return name == pattern
+		}
+	}
+
+	return
+}
+
+// matches reports whether the rule matches the class name, including its module if the rule has one.
+func (rule *filterRule) matches(name string) bool {
+	if rule.module != "" && rule.module != jdkModule(name) {
+		return false
+	}
+
+	return rule.match(name)
+}
+
+// arrayElementClass returns the name of the element class of an array class or an empty string for arrays of
+// primitives.
+func arrayElementClass(name string) string {
+	name = strings.TrimLeft(name, "[")
+	if !strings.HasPrefix(name, "L") || !strings.HasSuffix(name, ";") {
+		return ""
+	}
+
+	return name[1 : len(name)-1]
+}
+
+// limitError returns the FilterError for a limit which has been exceeded or nil.
+func limitError(class, name string, limit, value int64) error {
+	if limit < 0 || value <= limit {
+		return nil
+	}
+
+	return errors.WithStack(&FilterError{Class: class, Rule: fmt.Sprintf("%s=%d", name, limit)})
+}
+
+// check applies the filter to a class, arrayLength is -1 unless the class is an array being read.
+func (f *SerialFilter) check(class string, arrayLength int64, depth, refs, bytes int64) error {
+	for _, err := range []error{
+		limitError(class, "maxdepth", f.maxDepth, depth),
+		limitError(class, "maxrefs", f.maxRefs, refs),
+		limitError(class, "maxbytes", f.maxBytes, bytes),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	name := class
+
+	if strings.HasPrefix(name, "[") {
+		if err := limitError(class, "maxarray", f.maxArray, arrayLength); err != nil {
+			return err
+		}
+
+		if name = arrayElementClass(name); name == "" {
+			// arrays of primitives are not matched by patterns
+			return nil
+		}
+	}
+
+	for _, rule := range f.rules {
+		if rule.matches(name) {
+			if rule.reject {
+				return errors.WithStack(&FilterError{Class: class, Rule: rule.text})
+			}
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// checkFilter applies the parser's SerialFilter (if any) to a class at the current position of the stream.
+func (sop *SerializedObjectParser) checkFilter(class string, arrayLength int64) error {
+	if sop.filter == nil {
+		return nil
+	}
+
+	return sop.filter.check(class, arrayLength, int64(sop.objDepth), int64(sop.refs), sop.rd.pos)
+}
+
+// jdkModules maps well known JDK modules to the packages they contain.
+var jdkModules = map[string][]string{
+	"java.base": {
+		"java.io", "java.lang", "java.math", "java.net", "java.nio", "java.security", "java.text", "java.time",
+		"java.util", "javax.crypto", "javax.net", "javax.security.auth", "javax.security.cert", "jdk.internal", "sun",
+	},
+	"java.datatransfer": {"java.awt.datatransfer"},
+	"java.desktop": {
+		"java.applet", "java.awt", "java.beans", "javax.imageio", "javax.print", "javax.sound", "javax.swing",
+	},
+	"java.instrument":     {"java.lang.instrument"},
+	"java.logging":        {"java.util.logging"},
+	"java.management":     {"java.lang.management", "javax.management"},
+	"java.naming":         {"javax.naming", "com.sun.jndi"},
+	"java.prefs":          {"java.util.prefs"},
+	"java.rmi":            {"java.rmi", "javax.rmi", "sun.rmi"},
+	"java.scripting":      {"javax.script"},
+	"java.security.jgss":  {"javax.security.auth.kerberos", "org.ietf.jgss"},
+	"java.sql":            {"java.sql", "javax.sql"},
+	"java.sql.rowset":     {"javax.sql.rowset", "com.sun.rowset"},
+	"java.xml":            {"javax.xml", "org.w3c.dom", "org.xml.sax", "com.sun.org.apache"},
+	"java.xml.crypto":     {"javax.xml.crypto"},
+	"jdk.xml.dom":         {"org.w3c.dom.css", "org.w3c.dom.html", "org.w3c.dom.stylesheets", "org.w3c.dom.xpath"},
+	"java.transaction.xa": {"javax.transaction.xa"},
+}
+
+// jdkModule returns the name of the JDK module containing class or an empty string if it is unknown.
+func jdkModule(class string) (module string) {
+	longest := 0
+
+	for name, pkgs := range jdkModules {
+		for _, pkg := range pkgs {
+			if len(pkg) > longest && strings.HasPrefix(class, pkg+".") {
+				module, longest = name, len(pkg)
+			}
+		}
+	}
+
+	return
+}
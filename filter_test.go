@@ -0,0 +1,81 @@
+package jserial
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func parseWithFilter(t *testing.T, b []byte, pattern string) error {
+	filter, err := ParseSerialFilter(pattern)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetMaxDataBlockSize(len(b)), WithSerialFilter(filter))
+	_, err = sop.ParseSerializedObject()
+	return err
+}
+
+func filterRejection(t *testing.T, b []byte, pattern string) *FilterError {
+	var filterErr *FilterError
+	if err := parseWithFilter(t, b, pattern); !errors.As(err, &filterErr) {
+		t.Fatalf("%s: expected filter rejection, got %+v", pattern, err)
+	}
+	return filterErr
+}
+
+func TestSerialFilterAccept(t *testing.T) {
+	for _, pattern := range []string{
+		"",
+		"java.base/*;!*",
+		"java.lang.*;java.util.**;!*",
+		"java.util.Has*;java.lang.*;!*",
+		"maxdepth=10;maxrefs=20;maxbytes=1000;maxarray=2",
+		// the HashMap is at the top level and the Integer in it, class descriptors do not count
+		"maxdepth=2",
+	} {
+		if err := parseWithFilter(t, objs["hashMapStr"], pattern); err != nil {
+			t.Errorf("%s: unexpected error: %+v", pattern, err)
+		}
+	}
+}
+
+func TestSerialFilterReject(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		class   string
+		rule    string
+	}{
+		{"!java.util.HashMap", "java.util.HashMap", "!java.util.HashMap"},
+		{"java.lang.*;!*", "java.util.HashMap", "!*"},
+		{"java.lang.**;!java.*;!java.util.*", "java.util.HashMap", "!java.util.*"},
+		{"!java.base/java.lang.Number", "java.lang.Number", "!java.base/java.lang.Number"},
+		{"java.desktop/*;!java.base/*", "[Ljava.lang.Object;", "!java.base/*"},
+		{"maxdepth=1", "java.lang.Integer", "maxdepth=1"},
+		{"maxrefs=5", "java.lang.Integer", "maxrefs=5"},
+		{"maxbytes=64", "java.util.HashMap", "maxbytes=64"},
+		{"maxarray=1", "[Ljava.lang.Object;", "maxarray=1"},
+	} {
+		filterErr := filterRejection(t, objs["hashMapStr"], tc.pattern)
+		if filterErr.Class != tc.class || filterErr.Rule != tc.rule {
+			t.Errorf("%s: unexpected rejection %v", tc.pattern, filterErr)
+		}
+	}
+}
+
+func TestSerialFilterPrimitiveArrays(t *testing.T) {
+	if err := parseWithFilter(t, objs["primArray"], "java.lang.Object;!*"); err != nil {
+		t.Fail()
+	}
+	if filterRejection(t, objs["primArray"], "maxarray=2").Class != "[I" {
+		t.Fail()
+	}
+}
+
+func TestSerialFilterInvalid(t *testing.T) {
+	for _, pattern := range []string{"maxfoo=1", "maxdepth=-1", "maxrefs=x", "!", "/foo.Bar", "!java.base/"} {
+		if _, err := ParseSerialFilter(pattern); err == nil {
+			t.Errorf("%s: expected error", pattern)
+		}
+	}
+}
@@ -1,10 +1,22 @@
+//go:build gofuzz
 // +build gofuzz
 
 package jserial
 
+import "bytes"
+
 // Fuzz is the go-fuzz entrypoint for fuzzing serialized object parsing
 func Fuzz(data []byte) int {
-	if _, err := ParseSerializedObjectMinimal(data); err != nil {
+	sop := NewSerializedObjectParser(bytes.NewReader(data),
+		SetMaxStringLength(len(data)),
+		SetMaxBlockDataSize(len(data)),
+		SetMaxDepth(1000),
+		SetMaxHandles(10000),
+		SetMaxArrayLength(100000),
+		SetMaxInputBytes(1<<20),
+		SetMaxAllocBytes(16<<20),
+	)
+	if _, err := sop.ParseSerializedObjectMinimal(); err != nil {
 		return 0
 	}
 	return 1
@@ -1,3 +1,5 @@
 module github.com/jkeys089/jserial
 
-require github.com/pkg/errors v0.8.1
+go 1.20
+
+require github.com/pkg/errors v0.9.1
@@ -0,0 +1,39 @@
+package jserial
+
+// Handles is a snapshot of a parser's handle table, i.e. the class descriptors, objects and strings which can be
+// referenced by the rest of the stream.
+type Handles struct {
+	handles []interface{}
+}
+
+// Len returns the number of handles in the snapshot.
+func (h *Handles) Len() int {
+	return len(h.handles)
+}
+
+// SetSkipHeader makes the parser read streams without the STREAM_MAGIC and STREAM_VERSION header, e.g. the body of
+// a JRMP call or a fragment of a connection which only sent the header once.
+func SetSkipHeader(skip bool) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.skipHeader = skip
+		sop.headerRead = skip
+	}
+}
+
+// WithHandles seeds the parser's handle table with a snapshot so references to previously read values, e.g. class
+// descriptors sent earlier on the same connection, can be resolved.
+func WithHandles(handles *Handles) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.RestoreHandles(handles)
+	}
+}
+
+// SnapshotHandles returns a copy of the parser's handle table.
+func (sop *SerializedObjectParser) SnapshotHandles() *Handles {
+	return &Handles{handles: append([]interface{}(nil), sop.handles...)}
+}
+
+// RestoreHandles replaces the parser's handle table with a copy of a snapshot.
+func (sop *SerializedObjectParser) RestoreHandles(handles *Handles) {
+	sop.handles = append(sop.handles[:0], handles.handles...)
+}
@@ -0,0 +1,42 @@
+package jserial
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHeaderlessFragment(t *testing.T) {
+	sop := newTestParser(t, streamHex("", ""))
+	first, err := sop.ParseSerializedObjectMinimal()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	handles := sop.SnapshotHandles()
+	if handles.Len() != 2 {
+		t.Fatalf("unexpected number of handles %d", handles.Len())
+	}
+
+	// an object of the class descriptor read before, without a header
+	fragment := tcObject + tcReference + "00" + baseWireHandle + "01234567"
+	sop = newTestParser(t, fragment, SetSkipHeader(true), WithHandles(handles))
+	second, err := sop.ParseSerializedObjectMinimal()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("unexpected content %v", second)
+	}
+
+	// restoring a snapshot discards the handles added since
+	sop.RestoreHandles(handles)
+	if len(sop.handles) != 2 {
+		t.Errorf("unexpected number of handles %d", len(sop.handles))
+	}
+
+	if _, err = newTestParser(t, fragment, SetSkipHeader(true)).ParseSerializedObject(); err == nil {
+		t.Error("expected error without handles")
+	}
+	if _, err = newTestParser(t, fragment, WithHandles(handles)).ParseSerializedObject(); err == nil {
+		t.Error("expected error without skipping the header")
+	}
+}
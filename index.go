@@ -0,0 +1,190 @@
+package jserial
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// indexEntry locates a content in the stream.
+type indexEntry struct {
+	// offset is the position of the content's type code.
+	offset int64
+	// base is the number of handles assigned before the content.
+	base int
+}
+
+// Index locates the top level contents and handles of a serialized stream stored in an io.ReaderAt, so they can be
+// decoded on demand. Only class descriptors and strings are kept in memory while indexing, other values are decoded
+// when they are accessed and references to them are resolved through the index. An Index is not safe for concurrent
+// use.
+type Index struct {
+	ra        io.ReaderAt
+	size      int64
+	options   []Option
+	items     []indexEntry
+	handles   []indexEntry
+	resolved  map[int]interface{}
+	resolving map[int]bool
+}
+
+// BuildIndex reads the size bytes of the stream in ra to index its top level contents and handles. The options are
+// applied to the parser used for indexing and to those decoding values later, e.g. to set resource limits.
+func BuildIndex(ra io.ReaderAt, size int64, options ...Option) (*Index, error) {
+	sop := NewSerializedObjectParser(io.NewSectionReader(ra, 0, size), options...)
+	sop.indexing = true
+
+	if err := sop.header(); err != nil {
+		return nil, sop.parseError(err)
+	}
+
+	ix := &Index{
+		ra:        ra,
+		size:      size,
+		options:   options,
+		resolved:  map[int]interface{}{},
+		resolving: map[int]bool{},
+	}
+
+	for !sop.end() {
+		entry := indexEntry{offset: sop.rd.pos, base: len(sop.handles)}
+
+		if _, err := sop.Next(); err != nil {
+			return nil, err
+		}
+
+		ix.items = append(ix.items, entry)
+	}
+
+	ix.handles = sop.indexed
+
+	return ix, nil
+}
+
+// Len returns the number of top level contents.
+func (ix *Index) Len() int {
+	return len(ix.items)
+}
+
+// Handles returns the number of handles.
+func (ix *Index) Handles() int {
+	return len(ix.handles)
+}
+
+// Offset returns the position of the i-th top level content in the stream.
+func (ix *Index) Offset(i int) int64 {
+	return ix.items[i].offset
+}
+
+// Item decodes the i-th top level content.
+func (ix *Index) Item(i int) (interface{}, error) {
+	if i < 0 || i >= len(ix.items) {
+		return nil, errors.Errorf("item %d out of range", i)
+	}
+
+	content, _, err := ix.decode(ix.items[i])
+
+	return content, err
+}
+
+// Handle decodes the value of handle h, i.e. the value a reference to `0x7e0000 + h` resolves to. Handles are cached
+// once decoded.
+func (ix *Index) Handle(h int) (interface{}, error) {
+	if h < 0 || h >= len(ix.handles) {
+		return nil, errors.Errorf("handle %d out of range", h)
+	}
+
+	return ix.resolve(h)
+}
+
+// resolve decodes the content assigning handle h.
+func (ix *Index) resolve(h int) (interface{}, error) {
+	if h >= len(ix.handles) {
+		return nil, nil
+	}
+
+	if val, isResolved := ix.resolved[h]; isResolved {
+		return val, nil
+	}
+
+	if ix.resolving[h] {
+		return nil, errors.Errorf("cyclic reference to handle %d", h)
+	}
+
+	ix.resolving[h] = true
+	defer delete(ix.resolving, h)
+
+	entry := ix.handles[h]
+
+	_, handles, err := ix.decode(entry)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error resolving handle %d", h)
+	}
+
+	val := handles[h-entry.base]
+	ix.resolved[h] = val
+
+	return val, nil
+}
+
+// decode reads the content at entry and returns it and the handles it assigned.
+func (ix *Index) decode(entry indexEntry) (content interface{}, handles []interface{}, err error) {
+	options := append(append([]Option(nil), ix.options...), SetSkipHeader(true))
+
+	sop := NewSerializedObjectParser(io.NewSectionReader(ix.ra, entry.offset, ix.size-entry.offset), options...)
+	sop.rd.pos = entry.offset
+	sop.handleBase = entry.base
+	sop.resolveHandle = ix.resolve
+
+	if content, err = sop.Next(); err != nil {
+		return
+	}
+
+	return content, sop.handles, nil
+}
+
+// beginIndexEntry records the position of a content whose type code has just been read while indexing.
+func (sop *SerializedObjectParser) beginIndexEntry() {
+	if sop.indexing {
+		sop.contentStack = append(sop.contentStack, indexEntry{offset: sop.contentOffset, base: len(sop.handles)})
+	}
+}
+
+// endIndexEntry removes the entry added by beginIndexEntry.
+func (sop *SerializedObjectParser) endIndexEntry() {
+	if sop.indexing {
+		sop.contentStack = sop.contentStack[:len(sop.contentStack)-1]
+	}
+}
+
+// indexHandle records the content assigning the next handle while indexing.
+func (sop *SerializedObjectParser) indexHandle() {
+	if sop.indexing {
+		sop.indexed = append(sop.indexed, sop.contentStack[len(sop.contentStack)-1])
+	}
+}
+
+// retained returns the value kept in the handle table for obj. While indexing only class descriptors and strings are
+// kept, since they are needed to read the rest of the stream.
+func (sop *SerializedObjectParser) retained(obj interface{}) interface{} {
+	if !sop.indexing {
+		return obj
+	}
+
+	switch obj.(type) {
+	case *clazz, string:
+		return obj
+	default:
+		return nil
+	}
+}
+
+// skip consumes n bytes without decoding them.
+func (sop *SerializedObjectParser) skip(n int64) error {
+	if _, err := io.CopyN(ioutil.Discard, sop.rd, n); err != nil {
+		return errors.Wrap(err, "error skipping input")
+	}
+
+	return nil
+}
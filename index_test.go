@@ -0,0 +1,98 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func indexTestStream(t *testing.T) []byte {
+	someClass := tcClassDesc + someClassEnc + serialVer + scSerializable + "0001" + "49" + fooEnc + tcEndBlockData +
+		tcNull
+	intArrayClass := tcClassDesc + encodeStr("[I") + serialVer + scSerializable + "0000" + tcEndBlockData + tcNull
+
+	return decodeHex(t, streamMagic+streamVersion+
+		// handles 0 and 1
+		tcObject+someClass+"00000001"+
+		// handle 2, referencing the class of the first object
+		tcObject+tcReference+"00"+baseWireHandle+"00000002"+
+		// handles 3 and 4
+		tcArray+intArrayClass+"00000002"+"0000000300000004"+
+		// handle 5
+		tcString+encodeStr("bar")+
+		tcReference+"007e0001"+
+		tcBlockData+"03aabbcc")
+}
+
+func TestIndexItems(t *testing.T) {
+	b := indexTestStream(t)
+
+	expected, err := ParseSerializedObject(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	ix, err := BuildIndex(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if ix.Len() != len(expected) || ix.Handles() != 6 {
+		t.Fatalf("unexpected index of %d items and %d handles", ix.Len(), ix.Handles())
+	}
+	if ix.Offset(0) != 4 {
+		t.Errorf("unexpected offset %d", ix.Offset(0))
+	}
+
+	// decode the items out of order
+	for i := ix.Len() - 1; i >= 0; i-- {
+		item, err := ix.Item(i)
+		if err != nil {
+			t.Fatalf("unexpected error decoding item %d: %+v", i, err)
+		}
+		if !reflect.DeepEqual(item, expected[i]) {
+			t.Errorf("unexpected item %d: %v != %v", i, item, expected[i])
+		}
+	}
+}
+
+func TestIndexHandles(t *testing.T) {
+	b := indexTestStream(t)
+
+	ix, err := BuildIndex(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	sop := newTestParser(t, hex.EncodeToString(b))
+	if _, err = sop.ParseSerializedObject(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	// resolve the later handles first so earlier ones are decoded through references
+	for h := ix.Handles() - 1; h >= 0; h-- {
+		val, err := ix.Handle(h)
+		if err != nil {
+			t.Fatalf("unexpected error resolving handle %d: %+v", h, err)
+		}
+		if !reflect.DeepEqual(val, sop.handles[h]) {
+			t.Errorf("unexpected handle %d: %v != %v", h, val, sop.handles[h])
+		}
+	}
+
+	if _, err = ix.Handle(6); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err = ix.Item(-1); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestIndexTruncated(t *testing.T) {
+	b := indexTestStream(t)
+
+	if _, err := BuildIndex(bytes.NewReader(b), int64(len(b)-2)); err == nil {
+		t.Error("expected an error indexing a truncated stream")
+	}
+}
@@ -0,0 +1,335 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Undecodable replaces an object which could not be decoded when parsing in lenient mode.
+type Undecodable struct {
+	// Class is the name of the class of the object, if known.
+	Class string
+	// Offset is the position of the object in the stream.
+	Offset int64
+	// Err is the reason the object could not be decoded.
+	Err error
+}
+
+// MarshalJSON encodes the class, offset and error message.
+func (u *Undecodable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"class":  u.Class,
+		"offset": u.Offset,
+		"error":  u.Err.Error(),
+	})
+}
+
+// SetLenient enables lenient mode: objects which cannot be decoded are replaced by an *Undecodable wherever the
+// parser can resynchronize with the stream, and the errors are collected as warnings instead of failing the parse.
+// The parser resynchronizes
+//   - after a post processor fails, since the annotations it decodes are delimited by TC_ENDBLOCKDATA
+//   - at the TC_ENDBLOCKDATA following a failed annotation, e.g. an entry of a HashMap, if the underlying reader is an
+//     io.ReadSeeker, dropping the annotations after it
+//   - at the next top level object following a failed one, if the underlying reader is an io.ReadSeeker
+//
+// Otherwise parsing stops at the first top level object which fails. A top level object is resumed at if its class
+// descriptor is inline with a plausible class name and it is followed by the end of the stream or a type code. Since
+// the number of handles assigned in the skipped part of the stream is unknown, references to handles assigned after
+// the first failure recovered from, up to the next stream header of concatenated streams, are read as nil.
+func SetLenient(lenient bool) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.lenient = lenient
+	}
+}
+
+// Warnings returns the errors which were recovered from in lenient mode.
+func (sop *SerializedObjectParser) Warnings() []*ParseError {
+	return sop.warnings
+}
+
+// warn records err as a warning and returns it as a *ParseError.
+func (sop *SerializedObjectParser) warn(err error) *ParseError {
+	var parseErr *ParseError
+
+	errors.As(sop.parseError(err), &parseErr)
+	sop.warnings = append(sop.warnings, parseErr)
+
+	return parseErr
+}
+
+// markResynced records that part of the stream is skipped, the handles assigned so far keep their numbering.
+func (sop *SerializedObjectParser) markResynced() {
+	if !sop.resynced {
+		sop.resynced, sop.knownHandles = true, len(sop.handles)
+	}
+}
+
+// knownHandle reports whether the handle at index i of the handle table is known to be numbered as in the stream,
+// i.e. it was not assigned after skipping part of the stream.
+func (sop *SerializedObjectParser) knownHandle(i int) bool {
+	return !sop.resynced || i < sop.knownHandles
+}
+
+// recoverable reports whether err can be recovered from in lenient mode. Errors caused by the context, resource
+// limits or filters are never recovered from.
+func (sop *SerializedObjectParser) recoverable(err error) bool {
+	var (
+		limitErr  *LimitError
+		filterErr *FilterError
+	)
+
+	return sop.lenient && sop.contextErr() == nil && !errors.As(err, &limitErr) && !errors.As(err, &filterErr)
+}
+
+// initSeeker enables resynchronizing with the stream in lenient mode if rd is an io.ReadSeeker.
+func (sop *SerializedObjectParser) initSeeker(rd io.Reader) {
+	seeker, isSeeker := rd.(io.ReadSeeker)
+	if !sop.lenient || !isSeeker {
+		return
+	}
+
+	if base, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+		sop.seeker, sop.seekBase = seeker, base
+	}
+}
+
+// seekTo moves the parser to offset in the stream.
+func (sop *SerializedObjectParser) seekTo(offset int64) error {
+	if _, err := sop.seeker.Seek(sop.seekBase+offset, io.SeekStart); err != nil {
+		return errors.Wrap(err, "error seeking stream")
+	}
+
+	sop.rd.br.Reset(sop.seeker)
+	sop.rd.pos = offset
+	sop.rd.eof = false
+
+	return nil
+}
+
+// recover handles the error of the top level content starting at offset in lenient mode. It returns the placeholder
+// for the failed content and reads the next top level object which can be decoded, if any, into sop.pending.
+func (sop *SerializedObjectParser) recover(offset int64, err error) (placeholder interface{}, resumeErr error) {
+	undecodable := &Undecodable{Offset: offset, Err: sop.warn(err)}
+	if classes := sop.classPath(); len(classes) > 0 {
+		undecodable.Class = classes[0]
+	}
+
+	placeholder = undecodable
+
+	if sop.seeker == nil {
+		sop.stopped = true
+
+		return
+	}
+
+	handles := len(sop.handles)
+
+	// the bytes read before the failure belong to the failed content
+	candidate := offset + 1
+	if sop.rd.pos > candidate {
+		candidate = sop.rd.pos
+	}
+
+	for ; ; candidate++ {
+		if resumeErr = sop.contextErr(); resumeErr != nil {
+			return
+		}
+
+		if candidate, resumeErr = sop.nextCandidate(candidate); resumeErr != nil {
+			if errors.Cause(resumeErr) == io.EOF {
+				sop.stopped, resumeErr = true, nil
+			}
+
+			return
+		}
+
+		sop.handles = sop.handles[:handles]
+		sop.markResynced()
+		sop.resetPath()
+
+		var nxt interface{}
+
+		if nxt, err = sop.content(nil); err == nil && sop.atContentBoundary() {
+			sop.pending, sop.hasPending = nxt, true
+
+			return
+		}
+
+		if err != nil && !sop.recoverable(err) {
+			resumeErr = sop.parseError(err)
+
+			return
+		}
+	}
+}
+
+// nextCandidate returns the offset of the first byte sequence at or after offset which looks like the start of an
+// object or array with an inline class descriptor and positions the parser there.
+func (sop *SerializedObjectParser) nextCandidate(offset int64) (int64, error) {
+	if err := sop.seekTo(offset); err != nil {
+		return 0, err
+	}
+
+	for !sop.atObjectStart() {
+		if _, err := sop.rd.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+
+	return sop.rd.pos, nil
+}
+
+// atObjectStart reports whether the next bytes look like the start of an object or array with an inline class
+// descriptor of a plausible class.
+func (sop *SerializedObjectParser) atObjectStart() bool {
+	const (
+		tcClassDesc = 0x72
+		tcObject    = 0x73
+		tcArray     = 0x75
+	)
+
+	b, _ := sop.rd.Peek(4)
+	if len(b) < 4 || (b[0] != tcObject && b[0] != tcArray) || b[1] != tcClassDesc {
+		return false
+	}
+
+	// the class name follows the type codes
+	n := 4 + int(binary.BigEndian.Uint16(b[2:]))
+	name, _ := sop.rd.Peek(n)
+
+	return len(name) == n && plausibleClassName(name[4:])
+}
+
+// annotationState is the state of the parser before reading an annotation, restored when recovering from its
+// failure.
+type annotationState struct {
+	offset    int64
+	path      int
+	typeCodes int
+	softErr   error
+}
+
+// annotationState returns the state of the parser before reading an annotation.
+func (sop *SerializedObjectParser) annotationState() annotationState {
+	return annotationState{offset: sop.rd.pos, path: len(sop.path), typeCodes: len(sop.typeCodes), softErr: sop.softErr}
+}
+
+// recoverAnnotation handles the error of the annotation read from state in lenient mode. It returns the placeholder
+// for the failed content and skips to the TC_ENDBLOCKDATA ending the annotations. Objects with an inline class
+// descriptor found on the way are read as part of the failed content, so the end markers within them are skipped.
+func (sop *SerializedObjectParser) recoverAnnotation(state annotationState, err error) (placeholder interface{},
+	skipErr error) {
+	const tcEndBlockData = 0x78
+
+	undecodable := &Undecodable{Offset: state.offset, Err: sop.warn(err)}
+
+	for _, seg := range sop.path[state.path:] {
+		if seg.class != "" {
+			undecodable.Class = seg.class
+
+			break
+		}
+	}
+
+	sop.path, sop.typeCodes, sop.softErr = sop.path[:state.path], sop.typeCodes[:state.typeCodes], state.softErr
+	sop.markResynced()
+
+	// the bytes read before the failure belong to the failed content
+	pos := sop.rd.pos
+	if pos <= state.offset {
+		pos = state.offset + 1
+	}
+
+	for {
+		if skipErr = sop.seekTo(pos); skipErr != nil {
+			return
+		}
+
+		b, peekErr := sop.rd.Peek(1)
+		if peekErr != nil {
+			// the annotations do not end
+			return nil, err
+		}
+
+		if b[0] == tcEndBlockData {
+			return undecodable, nil
+		}
+
+		if sop.atObjectStart() {
+			nested, handles := sop.annotationState(), len(sop.handles)
+
+			_, nestedErr := sop.content(nil)
+			if nestedErr == nil {
+				pos = sop.rd.pos
+
+				continue
+			}
+
+			if !sop.recoverable(nestedErr) {
+				return nil, nestedErr
+			}
+
+			sop.path, sop.typeCodes, sop.softErr = sop.path[:nested.path], sop.typeCodes[:nested.typeCodes], nested.softErr
+			sop.handles = sop.handles[:handles]
+		}
+
+		pos++
+	}
+}
+
+// atContentBoundary reports whether the stream ends or continues with a type code which may follow a top level
+// content.
+func (sop *SerializedObjectParser) atContentBoundary() bool {
+	const (
+		tcEndBlockData = 0x78
+		streamMagic    = 0xac
+	)
+
+	b, err := sop.rd.Peek(1)
+	if err != nil {
+		return true
+	}
+
+	if b[0] == streamMagic {
+		return sop.concatenated
+	}
+
+	return b[0] != tcEndBlockData && b[0] >= typeMask && b[0]-typeMask <= typeNameMax
+}
+
+// plausibleClassName reports whether name is a class name or array class name a stream may hold, e.g.
+// `com.acme.Order$Line`, `[I` or `[Lcom.acme.Order;`.
+func plausibleClassName(name []byte) bool {
+	elem := bytes.TrimLeft(name, "[")
+
+	switch {
+	case len(elem) == len(name):
+		return plausibleBinaryName(elem)
+	case len(elem) == 1:
+		return bytes.IndexByte([]byte("BCDFIJSZ"), elem[0]) >= 0
+	default:
+		return len(elem) > 2 && elem[0] == 'L' && elem[len(elem)-1] == ';' && plausibleBinaryName(elem[1:len(elem)-1])
+	}
+}
+
+// plausibleBinaryName reports whether name is made of dot separated Java identifiers.
+func plausibleBinaryName(name []byte) bool {
+	for _, ident := range bytes.Split(name, []byte(".")) {
+		if len(ident) == 0 || ('0' <= ident[0] && ident[0] <= '9') {
+			return false
+		}
+
+		for _, c := range ident {
+			isLetter := ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_' || c == '$' || c >= 0x80
+			if !isLetter && !('0' <= c && c <= '9') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,161 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const wrongHashSetHex = tcObject + tcClassDesc + "0011" + "6a6176612e7574696c2e48617368536574" + "ba44859596b8b734" +
+	"03" + "0000" + tcEndBlockData + tcNull + tcBlockData + "0c" + "00000003" + "00000000" + "00000003" + tcString +
+	"0003666f6f" + tcEndBlockData
+
+func parseLenient(t *testing.T, rd io.Reader) ([]interface{}, []*ParseError) {
+	sop := NewSerializedObjectParser(rd, SetLenient(true))
+	content, err := sop.ParseSerializedObjectMinimal()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	return content, sop.Warnings()
+}
+
+func decodeHex(t *testing.T, hexStr string) []byte {
+	b, err := hex.DecodeString(hexStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLenientPostProc(t *testing.T) {
+	b := decodeHex(t, streamMagic+streamVersion+tcArray+tcClassDesc+encodeStr("[Ljava.lang.Object;")+serialVer+
+		scSerializable+"0000"+tcEndBlockData+tcNull+"00000002"+wrongHashSetHex+tcString+encodeStr("bar"))
+	content, warnings := parseLenient(t, bytes.NewReader(b))
+
+	arr, _ := content[0].([]interface{})
+	undecodable, isUndecodable := arr[0].(*Undecodable)
+	if !isUndecodable || undecodable.Class != "java.util.HashSet" || undecodable.Offset != 44 || arr[1] != "bar" {
+		t.Fatalf("unexpected content %v", content)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "incorrect number of elements") {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+func TestLenientResync(t *testing.T) {
+	hexStr := streamHex("flags", "00") + tcObject + tcClassDesc + encodeStr("Other") + serialVer + scSerializable +
+		"0001" + hex.EncodeToString([]byte("I")) + fooEnc + tcEndBlockData + tcNull + "0000002a"
+	content, warnings := parseLenient(t, bytes.NewReader(decodeHex(t, hexStr)))
+
+	expected := []interface{}{
+		&Undecodable{Class: "SomeClass", Offset: 4, Err: warnings[0]},
+		map[string]interface{}{"foo": int32(42)},
+	}
+	if len(warnings) != 1 || !reflect.DeepEqual(content, expected) {
+		t.Errorf("unexpected content %v, warnings %v", content, warnings)
+	}
+
+	if _, err := ParseSerializedObject(decodeHex(t, hexStr)); err == nil {
+		t.Error("expected error without lenient mode")
+	}
+
+	// without seeking parsing stops at the failed object
+	content, _ = parseLenient(t, struct{ io.Reader }{bytes.NewReader(decodeHex(t, hexStr))})
+	if len(content) != 1 {
+		t.Errorf("unexpected content %v", content)
+	}
+
+	js, err := json.Marshal(content[0])
+	if err != nil || !strings.Contains(string(js), `"class":"SomeClass"`) {
+		t.Errorf("unexpected json %s, error %+v", js, err)
+	}
+}
+
+func TestLenientResyncReferences(t *testing.T) {
+	strField := func(name string) string {
+		return hex.EncodeToString([]byte("L")) + encodeStr(name) + tcString + encodeStr("Ljava/lang/String;")
+	}
+
+	// the string "t" assigned a handle after the failure is skipped, so the reference to "x" cannot be resolved
+	hexStr := streamMagic + streamVersion + tcArray + tcClassDesc + encodeStr("[Ljava.lang.Object;") + serialVer +
+		scSerializable + "0000" + tcEndBlockData + tcNull + "00000003" + tcString + encodeStr("s") + "6f" + tcString +
+		encodeStr("t") + tcObject + tcClassDesc + encodeStr("Other") + serialVer + scSerializable + "0003" +
+		strField("a") + strField("b") + strField("c") + tcEndBlockData + tcNull + tcString + encodeStr("x") + tcString +
+		encodeStr("y") + tcReference + "007e0009"
+	content, warnings := parseLenient(t, bytes.NewReader(decodeHex(t, hexStr)))
+
+	if len(content) != 2 || len(warnings) != 1 {
+		t.Fatalf("unexpected content %v, warnings %v", content, warnings)
+	}
+
+	if expected := map[string]interface{}{"a": "x", "b": "y", "c": nil}; !reflect.DeepEqual(content[1], expected) {
+		t.Errorf("expected %v, got %v", expected, content[1])
+	}
+}
+
+func TestPlausibleClassName(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"com.acme.Order$Line":   true,
+		"Other":                 true,
+		"[I":                    true,
+		"[[Lcom.acme.Order;":    true,
+		"":                      false,
+		"[":                     false,
+		"[X":                    false,
+		"[L;":                   false,
+		"com..Order":            false,
+		"com.acme.1Order":       false,
+		"some text":             false,
+		"[Lcom.acme.Order":      false,
+		"java.util.HashMap\x00": false,
+	} {
+		if plausibleClassName([]byte(name)) != expected {
+			t.Errorf("expected %v for %q", expected, name)
+		}
+	}
+}
+
+func TestLenientAnnotation(t *testing.T) {
+	inner := tcObject + tcClassDesc + encodeStr("Inner") + serialVer + scSerializable + "0000" + tcEndBlockData + tcNull
+	// version 1 external content is not supported, the end markers of the nested object are skipped with it
+	bad := tcObject + tcClassDesc + encodeStr("Bad") + serialVer + "04" + "0000" + tcEndBlockData + tcNull + "0102" + inner
+	hexStr := streamMagic + streamVersion + tcObject + tcClassDesc + encodeStr("Holder") + serialVer + "03" +
+		"0000" + tcEndBlockData + tcNull + tcString + encodeStr("before") + bad + tcEndBlockData + tcString +
+		encodeStr("after")
+	content, warnings := parseLenient(t, bytes.NewReader(decodeHex(t, hexStr)))
+
+	if len(content) != 2 || content[1] != "after" || len(warnings) != 1 {
+		t.Fatalf("unexpected content %v, warnings %v", content, warnings)
+	}
+
+	holder, _ := content[0].(map[string]interface{})
+	expected := []interface{}{"before", &Undecodable{Class: "Bad", Offset: 36, Err: warnings[0]}}
+
+	if !reflect.DeepEqual(holder["@"], expected) {
+		t.Errorf("expected %v, got %v", expected, holder["@"])
+	}
+}
+
+func TestLenientReferenceBeforeRecovery(t *testing.T) {
+	point := tcClassDesc + encodeStr("Point") + serialVer + scSerializable + "0001" + hex.EncodeToString([]byte("I")) +
+		encodeStr("x") + tcEndBlockData + tcNull
+	bad := tcObject + tcClassDesc + encodeStr("Bad") + serialVer + "04" + "0000" + tcEndBlockData + tcNull + "0102"
+	holder := tcObject + tcClassDesc + encodeStr("Holder") + serialVer + "03" + "0000" + tcEndBlockData + tcNull + bad +
+		tcEndBlockData
+	// the class descriptor of Point was assigned its handle before the failure
+	hexStr := streamMagic + streamVersion + tcObject + point + "00000001" + holder + tcObject + tcReference + "007e0000" +
+		"00000002"
+	content, warnings := parseLenient(t, bytes.NewReader(decodeHex(t, hexStr)))
+
+	if len(content) != 3 || len(warnings) != 1 {
+		t.Fatalf("unexpected content %v, warnings %v", content, warnings)
+	}
+
+	if expected := map[string]interface{}{"x": int32(2)}; !reflect.DeepEqual(content[2], expected) {
+		t.Errorf("expected %v, got %v", expected, content[2])
+	}
+}
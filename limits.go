@@ -0,0 +1,207 @@
+package jserial
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Errors identifying the resource limit which has been exceeded, use errors.Is to test for them.
+var (
+	ErrMaxDepth         = errors.New("maximum nesting depth exceeded")
+	ErrMaxHandles       = errors.New("maximum number of handles exceeded")
+	ErrMaxArrayLength   = errors.New("maximum array length exceeded")
+	ErrMaxInputBytes    = errors.New("maximum input size exceeded")
+	ErrMaxAllocBytes    = errors.New("maximum allocation size exceeded")
+	ErrMaxStringLength  = errors.New("maximum string length exceeded")
+	ErrMaxBlockDataSize = errors.New("maximum block data size exceeded")
+)
+
+// LimitError is returned when a stream exceeds one of the parser's resource limits.
+type LimitError struct {
+	// Limit is one of ErrMaxDepth, ErrMaxHandles, ErrMaxArrayLength, ErrMaxInputBytes, ErrMaxAllocBytes,
+	// ErrMaxStringLength or ErrMaxBlockDataSize.
+	Limit error
+	// Max is the configured maximum.
+	Max int64
+	// Value is the value which exceeded the maximum.
+	Value int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %d exceeds %d", e.Limit, e.Value, e.Max)
+}
+
+// Unwrap returns the sentinel error identifying the limit.
+func (e *LimitError) Unwrap() error {
+	return e.Limit
+}
+
+// Approximate sizes used to account for allocations.
+const (
+	interfaceSize = 16
+	mapEntrySize  = 48
+)
+
+// Default limits of the length of strings and arrays and of the size of block data. Strings and block data are
+// allocated at once, arrays grow as their elements are read.
+const (
+	DefaultMaxStringLength  = 16 << 20
+	DefaultMaxBlockDataSize = 16 << 20
+	DefaultMaxArrayLength   = 16 << 20
+)
+
+// limits holds the parser's resource limits, zero means unlimited.
+type limits struct {
+	maxDepth         int
+	maxHandles       int
+	maxArrayLength   int
+	maxStringLength  int
+	maxBlockDataSize int
+	maxInputBytes    int64
+	maxAllocBytes    int64
+	allocated        int64
+}
+
+// defaultLimits returns the limits of a new parser.
+func defaultLimits() limits {
+	return limits{
+		maxArrayLength:   DefaultMaxArrayLength,
+		maxStringLength:  DefaultMaxStringLength,
+		maxBlockDataSize: DefaultMaxBlockDataSize,
+	}
+}
+
+// inMemoryLimits lifts the default limits of the length of strings, block data and arrays for a parser reading from
+// a slice, whose input bounds them. Options applied after it still set them.
+func inMemoryLimits(sop *SerializedObjectParser) {
+	sop.limits.maxArrayLength, sop.limits.maxStringLength, sop.limits.maxBlockDataSize = 0, 0, 0
+}
+
+// SetMaxDepth sets the maximum nesting depth of objects, arrays and class descriptors in the stream.
+func SetMaxDepth(maxDepth int) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.limits.maxDepth = maxDepth
+	}
+}
+
+// SetMaxHandles sets the maximum number of handles (objects, strings, arrays, classes and class descriptors which
+// can be referenced later in the stream).
+func SetMaxHandles(maxHandles int) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.limits.maxHandles = maxHandles
+	}
+}
+
+// SetMaxArrayLength sets the maximum number of elements of an array, DefaultMaxArrayLength by default unless the
+// input is a slice.
+func SetMaxArrayLength(maxLength int) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.limits.maxArrayLength = maxLength
+	}
+}
+
+// SetMaxStringLength sets the maximum length in bytes of a string, class or field name, DefaultMaxStringLength by
+// default unless the input is a slice.
+func SetMaxStringLength(maxLength int) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.limits.maxStringLength = maxLength
+	}
+}
+
+// SetMaxBlockDataSize sets the maximum size in bytes of block data, DefaultMaxBlockDataSize by default unless the
+// input is a slice.
+func SetMaxBlockDataSize(maxSize int) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.limits.maxBlockDataSize = maxSize
+	}
+}
+
+// SetMaxInputBytes sets the maximum number of bytes read from the stream, including the stream header.
+func SetMaxInputBytes(maxBytes int64) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.limits.maxInputBytes = maxBytes
+	}
+}
+
+// SetMaxAllocBytes sets the maximum number of bytes allocated for decoded values. Strings and block data are
+// accounted for exactly while objects, arrays and handles are accounted for using an estimate of their size.
+func SetMaxAllocBytes(maxBytes int64) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.limits.maxAllocBytes = maxBytes
+	}
+}
+
+// newLimitError returns a LimitError with a stack trace.
+func newLimitError(sentinel error, limit, value int64) error {
+	return errors.WithStack(&LimitError{Limit: sentinel, Max: limit, Value: value})
+}
+
+// checkDepth ensures one more level of nesting is allowed.
+func (sop *SerializedObjectParser) checkDepth() error {
+	if limit := sop.limits.maxDepth; limit > 0 && sop.depth >= limit {
+		return newLimitError(ErrMaxDepth, int64(limit), int64(sop.depth+1))
+	}
+
+	return nil
+}
+
+// checkHandles ensures one more handle is allowed.
+func (sop *SerializedObjectParser) checkHandles() error {
+	if limit := sop.limits.maxHandles; limit > 0 && len(sop.handles) >= limit {
+		return newLimitError(ErrMaxHandles, int64(limit), int64(len(sop.handles)+1))
+	}
+
+	return sop.alloc(interfaceSize)
+}
+
+// checkArrayLength ensures an array with size elements is allowed.
+func (sop *SerializedObjectParser) checkArrayLength(size int32) error {
+	if size < 0 {
+		return errors.Errorf("invalid array size %d", size)
+	}
+
+	if limit := sop.limits.maxArrayLength; limit > 0 && int(size) > limit {
+		return newLimitError(ErrMaxArrayLength, int64(limit), int64(size))
+	}
+
+	return nil
+}
+
+// checkStringLength ensures a string of n bytes is allowed.
+func (sop *SerializedObjectParser) checkStringLength(n int64) error {
+	if limit := sop.limits.maxStringLength; limit > 0 && n > int64(limit) {
+		return newLimitError(ErrMaxStringLength, int64(limit), n)
+	}
+
+	return nil
+}
+
+// checkBlockDataSize ensures block data of n bytes is allowed.
+func (sop *SerializedObjectParser) checkBlockDataSize(n int64) error {
+	if limit := sop.limits.maxBlockDataSize; limit > 0 && n > int64(limit) {
+		return newLimitError(ErrMaxBlockDataSize, int64(limit), n)
+	}
+
+	return nil
+}
+
+// checkInput ensures n more bytes may be read from the stream.
+func (sop *SerializedObjectParser) checkInput(n int64) error {
+	if limit := sop.limits.maxInputBytes; limit > 0 && sop.rd.pos+n > limit {
+		return newLimitError(ErrMaxInputBytes, limit, sop.rd.pos+n)
+	}
+
+	return nil
+}
+
+// alloc accounts for n more bytes allocated for decoded values.
+func (sop *SerializedObjectParser) alloc(n int64) error {
+	sop.limits.allocated += n
+
+	if limit := sop.limits.maxAllocBytes; limit > 0 && sop.limits.allocated > limit {
+		return newLimitError(ErrMaxAllocBytes, limit, sop.limits.allocated)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package jserial
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseWithLimits(b []byte, options ...Option) error {
+	options = append([]Option{SetMaxDataBlockSize(len(b))}, options...)
+	_, err := NewSerializedObjectParser(bytes.NewReader(b), options...).ParseSerializedObject()
+	return err
+}
+
+func TestLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		option Option
+		limit  error
+	}{
+		{"depth", SetMaxDepth(2), ErrMaxDepth},
+		{"handles", SetMaxHandles(5), ErrMaxHandles},
+		{"array", SetMaxArrayLength(1), ErrMaxArrayLength},
+		{"input", SetMaxInputBytes(64), ErrMaxInputBytes},
+		{"alloc", SetMaxAllocBytes(64), ErrMaxAllocBytes},
+		{"string", SetMaxStringLength(4), ErrMaxStringLength},
+		{"block data", SetMaxBlockDataSize(4), ErrMaxBlockDataSize},
+	} {
+		err := parseWithLimits(objs["hashMapStr"], tc.option)
+		var limitErr *LimitError
+		if !errors.Is(err, tc.limit) || !errors.As(err, &limitErr) || limitErr.Value <= limitErr.Max {
+			t.Errorf("%s: unexpected error %+v", tc.name, err)
+		}
+	}
+}
+
+func TestLimitsNotExceeded(t *testing.T) {
+	err := parseWithLimits(objs["hashMapStr"],
+		SetMaxDepth(10), SetMaxHandles(20), SetMaxArrayLength(2), SetMaxInputBytes(1024), SetMaxAllocBytes(4096))
+	if err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func TestLimitsCorpus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		option Option
+		limit  error
+	}{
+		{"limit_depth", SetMaxDepth(1000), ErrMaxDepth},
+		{"limit_array", SetMaxArrayLength(100000), ErrMaxArrayLength},
+		{"limit_handles", SetMaxHandles(10000), ErrMaxHandles},
+		{"limit_bytes", SetMaxInputBytes(32 << 10), ErrMaxInputBytes},
+		{"limit_bytes", SetMaxAllocBytes(32 << 10), ErrMaxAllocBytes},
+	} {
+		b, err := ioutil.ReadFile(filepath.Join("fuzzdata", "corpus", tc.name))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if err = parseWithLimits(b, tc.option); !errors.Is(err, tc.limit) {
+			t.Errorf("%s: expected %v, got %+v", tc.name, tc.limit, err)
+		}
+	}
+}
+
+func TestNegativeArraySize(t *testing.T) {
+	b := append([]byte{}, objs["primArray"]...)
+	// the size of the first array follows its class descriptor
+	idx := bytes.Index(b, []byte{0x78, 0x70, 0x00, 0x00, 0x00, 0x02})
+	copy(b[idx+2:], []byte{0xff, 0xff, 0xff, 0xff})
+	if err := parseWithLimits(b); err == nil {
+		t.Fail()
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	// strings longer than the reader's buffer are read from a stream
+	str := strings.Repeat("x", 4*bufferSize)
+	b := decodeHex(t, streamMagic+streamVersion+tcString+encodeStr(str))
+	content, err := NewSerializedObjectParser(bytes.NewReader(b)).ParseSerializedObject()
+	if err != nil || !reflect.DeepEqual(content, []interface{}{str}) {
+		t.Errorf("unexpected content %v, error: %+v", content, err)
+	}
+
+	// an array is not allocated before its length is checked
+	b = decodeHex(t, streamMagic+streamVersion+tcArray+tcClassDesc+encodeStr("[J")+serialVer+scSerializable+"0000"+
+		tcEndBlockData+tcNull+fmt.Sprintf("%08x", DefaultMaxArrayLength+1))
+	_, err = NewSerializedObjectParser(bytes.NewReader(b)).ParseSerializedObject()
+	if !errors.Is(err, ErrMaxArrayLength) {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func TestInMemoryLimits(t *testing.T) {
+	array := decodeHex(t, streamMagic+streamVersion+tcArray+tcClassDesc+encodeStr("[J")+serialVer+scSerializable+
+		"0000"+tcEndBlockData+tcNull+fmt.Sprintf("%08x", DefaultMaxArrayLength+1))
+	blockData := decodeHex(t, streamMagic+streamVersion+tcBlockDataLong+"7fffffff"+"aabbcc")
+
+	// the input bounds the length of arrays and block data read from a slice, it is exhausted before any default limit
+	for name, b := range map[string][]byte{"array": array, "block data": blockData} {
+		if _, err := ParseSerializedObject(b); !errors.Is(err, ErrTruncated) {
+			t.Errorf("%s: unexpected error: %+v", name, err)
+		}
+		if _, err := NewSerializedObjectParserBytes(b).ParseSerializedObject(); !errors.Is(err, ErrTruncated) {
+			t.Errorf("%s: unexpected error: %+v", name, err)
+		}
+	}
+
+	// limits set explicitly still apply
+	_, err := NewSerializedObjectParserBytes(array, SetMaxArrayLength(DefaultMaxArrayLength)).ParseSerializedObject()
+	if !errors.Is(err, ErrMaxArrayLength) {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	_, err = NewSerializedObjectParserBytes(blockData, SetMaxBlockDataSize(2)).ParseSerializedObject()
+	if !errors.Is(err, ErrMaxBlockDataSize) {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func TestSetBufferSize(t *testing.T) {
+	b := objs["hashMapStr"]
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetBufferSize(64))
+	if br, isBufio := sop.rd.br.(*bufio.Reader); !isBufio || br.Size() != 64 {
+		t.Fatal("unexpected reader")
+	}
+	if _, err := sop.ParseSerializedObject(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
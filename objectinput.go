@@ -0,0 +1,192 @@
+package jserial
+
+import (
+	"github.com/pkg/errors"
+)
+
+// The methods in this file mirror java.io.ObjectInputStream so code reading streams written with a mix of
+// primitives (e.g. out.writeInt) and objects (out.writeObject) can be ported line by line. Primitive data is read
+// from the block data at the current position of the stream, transparently spanning block boundaries. The stream
+// header is read by the first call if necessary.
+
+// peekType returns the name of the next type in the stream without consuming it.
+func (sop *SerializedObjectParser) peekType() (name string, err error) {
+	var b []byte
+
+	if b, err = sop.rd.Peek(1); err != nil {
+		return
+	}
+
+	tc := b[0] - typeMask
+	if tc > typeNameMax {
+		err = tagf(ErrUnknownTypeCode, "unknown type %#x", b[0])
+
+		return
+	}
+
+	return typeNames[tc], nil
+}
+
+// nextBlock reads the next block data from the stream.
+func (sop *SerializedObjectParser) nextBlock() error {
+	name, err := sop.peekType()
+	if err != nil {
+		return err
+	}
+
+	if name != "BlockData" && name != "BlockDataLong" {
+		return tagf(ErrUnexpectedTypeCode, "%s found where block data was expected", name)
+	}
+
+	sop.readingBlock = true
+	data, err := sop.content(nil)
+	sop.readingBlock = false
+
+	if err != nil {
+		return errors.Wrap(err, "error reading block data")
+	}
+
+	sop.block, _ = data.([]byte)
+
+	return nil
+}
+
+// Available returns the number of bytes left in the current block which can be read without reading more input.
+func (sop *SerializedObjectParser) Available() int {
+	return len(sop.block)
+}
+
+// ReadFully reads exactly len(p) bytes of block data into p.
+func (sop *SerializedObjectParser) ReadFully(p []byte) error {
+	if err := sop.header(); err != nil {
+		return err
+	}
+
+	for len(p) > 0 {
+		if len(sop.block) == 0 {
+			if err := sop.nextBlock(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		n := copy(p, sop.block)
+		p = p[n:]
+		sop.block = sop.block[n:]
+	}
+
+	return nil
+}
+
+// SkipBytes skips exactly n bytes of block data.
+func (sop *SerializedObjectParser) SkipBytes(n int) error {
+	return sop.ReadFully(make([]byte, n))
+}
+
+// ReadBoolean reads a boolean, see ObjectInputStream#readBoolean.
+func (sop *SerializedObjectParser) ReadBoolean() (bool, error) {
+	return sop.in.readBoolean()
+}
+
+// ReadByte reads a byte, see ObjectInputStream#readByte. Convert the result with int8 to get Java's signed value.
+func (sop *SerializedObjectParser) ReadByte() (byte, error) {
+	return sop.in.readByte()
+}
+
+// ReadShort reads a short, see ObjectInputStream#readShort.
+func (sop *SerializedObjectParser) ReadShort() (int16, error) {
+	x, err := sop.in.readUnsignedShort()
+
+	return int16(x), err
+}
+
+// ReadUnsignedShort reads an unsigned short, see ObjectInputStream#readUnsignedShort.
+func (sop *SerializedObjectParser) ReadUnsignedShort() (uint16, error) {
+	return sop.in.readUnsignedShort()
+}
+
+// ReadChar reads a char, see ObjectInputStream#readChar.
+func (sop *SerializedObjectParser) ReadChar() (rune, error) {
+	x, err := sop.in.readUnsignedShort()
+
+	return rune(x), err
+}
+
+// ReadInt reads an int, see ObjectInputStream#readInt.
+func (sop *SerializedObjectParser) ReadInt() (int32, error) {
+	return sop.in.readInt()
+}
+
+// ReadLong reads a long, see ObjectInputStream#readLong.
+func (sop *SerializedObjectParser) ReadLong() (int64, error) {
+	return sop.in.readLong()
+}
+
+// ReadFloat reads a float, see ObjectInputStream#readFloat.
+func (sop *SerializedObjectParser) ReadFloat() (float32, error) {
+	return sop.in.readFloat()
+}
+
+// ReadDouble reads a double, see ObjectInputStream#readDouble.
+func (sop *SerializedObjectParser) ReadDouble() (float64, error) {
+	return sop.in.readDouble()
+}
+
+// ReadUTF reads a string in modified UTF-8 format, see ObjectInputStream#readUTF.
+func (sop *SerializedObjectParser) ReadUTF() (string, error) {
+	return sop.in.readUTF()
+}
+
+// ReadObject reads the next object, see ObjectInputStream#readObject. It fails if the current block still contains
+// data or the next item in the stream is block data, and returns io.EOF at the end of the stream.
+func (sop *SerializedObjectParser) ReadObject() (obj interface{}, err error) {
+	if err = sop.header(); err != nil {
+		return
+	}
+
+	if len(sop.block) > 0 {
+		err = errors.Errorf("%d bytes of block data found where an object was expected", len(sop.block))
+
+		return
+	}
+
+	var name string
+
+	// the type code of a content whose class was read by PeekClass has been consumed
+	for !sop.hasPeeked() {
+		if name, err = sop.peekType(); err != nil {
+			return
+		}
+
+		if name != "BlockData" && name != "BlockDataLong" {
+			break
+		}
+
+		// skip empty blocks like ObjectInputStream does
+		if err = sop.nextBlock(); err != nil {
+			return
+		}
+
+		if len(sop.block) > 0 {
+			err = errors.Errorf("%d bytes of block data found where an object was expected", len(sop.block))
+
+			return
+		}
+	}
+
+	if name == "EndBlockData" {
+		err = tagf(ErrUnexpectedTypeCode, "end of block data found where an object was expected")
+
+		return
+	}
+
+	sop.resetPath()
+
+	if obj, err = sop.content(nil); err != nil {
+		// the object has already started so running out of input is reported as ErrTruncated
+		err = sop.parseError(err)
+	}
+
+	return
+}
@@ -0,0 +1,70 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func newTestParser(t *testing.T, hexStr string, options ...Option) *SerializedObjectParser {
+	b, err := hex.DecodeString(hexStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSerializedObjectParser(bytes.NewReader(b), append([]Option{SetMaxDataBlockSize(len(b))}, options...)...)
+}
+
+func TestObjectInputStreamMixed(t *testing.T) {
+	// out.writeInt(5); out.writeUTF("foo"); out.writeObject("bar"); out.writeLong(7) split across two blocks
+	sop := newTestParser(t, streamMagic+streamVersion+tcBlockData+"09"+"00000005"+"0003666f6f"+
+		tcString+encodeStr("bar")+tcBlockData+"03"+"000000"+tcBlockData+"00"+tcBlockData+"05"+"0000000007")
+
+	if i, err := sop.ReadInt(); err != nil || i != 5 {
+		t.Fail()
+	}
+	if s, err := sop.ReadUTF(); err != nil || s != "foo" {
+		t.Fail()
+	}
+	if _, err := sop.ReadInt(); err == nil {
+		t.Fail()
+	}
+	if obj, err := sop.ReadObject(); err != nil || obj != "bar" {
+		t.Fail()
+	}
+	if l, err := sop.ReadLong(); err != nil || l != 7 {
+		t.Fail()
+	}
+	if _, err := sop.ReadObject(); err != io.EOF {
+		t.Fail()
+	}
+}
+
+func TestObjectInputStreamDataBeforeObject(t *testing.T) {
+	sop := newTestParser(t, streamMagic+streamVersion+tcBlockData+"04"+"00000005"+tcString+encodeStr("bar"))
+
+	if _, err := sop.ReadObject(); err == nil {
+		t.Fail()
+	}
+	if b, err := sop.ReadByte(); err != nil || b != 0 || sop.Available() != 3 {
+		t.Fail()
+	}
+	if err := sop.SkipBytes(3); err != nil {
+		t.Fail()
+	}
+	if obj, err := sop.ReadObject(); err != nil || obj != "bar" {
+		t.Fail()
+	}
+}
+
+func TestObjectInputStreamThenParse(t *testing.T) {
+	sop := newTestParser(t, streamMagic+streamVersion+tcBlockData+"06"+"00000005"+"abcd"+tcString+encodeStr("bar"))
+
+	if i, err := sop.ReadInt(); err != nil || i != 5 {
+		t.Fail()
+	}
+	content, err := sop.ParseSerializedObject()
+	if err != nil || len(content) != 2 || !bytes.Equal(content[0].([]byte), []byte{0xab, 0xcd}) || content[1] != "bar" {
+		t.Fail()
+	}
+}
@@ -0,0 +1,31 @@
+package jserial
+
+// incompleteKey is set on objects which are only partially read because the stream is truncated.
+const incompleteKey = "@incomplete"
+
+// incompleteFieldKey holds the name of the field of an object which was being read when the stream ended.
+const incompleteFieldKey = "@incompleteField"
+
+// PartialArray replaces an array which was being read when a truncated stream ended.
+type PartialArray struct {
+	// Elements holds the elements read so far, of the type of a complete array, e.g. []interface{} or []int32.
+	Elements interface{}
+	// Length is the length of the array in the stream.
+	Length int
+}
+
+// SetPartialResults makes the parser return what has been read from a truncated stream along with the *ParseError
+// reporting ErrTruncated and the offset at which the input ended. Objects which were being read when the input ended
+// are returned with the fields read so far, an "@incomplete" key set to true and an "@incompleteField" key holding the
+// name of the field being read, if any. Arrays are replaced by a *PartialArray holding the elements read so far.
+func SetPartialResults(partial bool) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.partialResults = partial
+	}
+}
+
+// partial reports whether a partial result should be kept for err, i.e. partial results are enabled and the input
+// ended.
+func (sop *SerializedObjectParser) partial(err error) bool {
+	return err != nil && sop.partialResults && sop.rd.eof
+}
@@ -0,0 +1,68 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPartialResults(t *testing.T) {
+	item := encodeStr("com.acme.Item")
+	itemArray := encodeStr("[Lcom.acme.Item;")
+	b := decodeHex(t, streamPrefix+tcClassDesc+encodeStr("com.acme.Order")+serialVer+scSerializable+"0002"+
+		hex.EncodeToString([]byte("I"))+encodeStr("id")+
+		hex.EncodeToString([]byte("["))+encodeStr("items")+tcString+itemArray+tcEndBlockData+tcNull+"00000007"+
+		tcArray+tcClassDesc+itemArray+serialVer+scSerializable+"0000"+tcEndBlockData+tcNull+"00000002"+
+		tcObject+tcClassDesc+item+serialVer+scSerializable+"0002"+hex.EncodeToString([]byte("I"))+
+		encodeStr("price")+hex.EncodeToString([]byte("I"))+encodeStr("qty")+tcEndBlockData+tcNull+"0000002a"+
+		"00000001"+tcObject+tcReference+"007e0005"+"0000002b"+"00")
+
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetPartialResults(true))
+	content, err := sop.ParseSerializedObjectMinimal()
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || !errors.Is(err, ErrTruncated) || parseErr.Offset != int64(len(b)) {
+		t.Fatalf("unexpected error %+v", err)
+	}
+
+	expected := []interface{}{map[string]interface{}{
+		"id": int32(7),
+		"items": &PartialArray{Elements: []interface{}{
+			map[string]interface{}{"price": int32(42), "qty": int32(1)},
+			map[string]interface{}{"price": int32(43), incompleteKey: true, incompleteFieldKey: "qty"},
+		}, Length: 2},
+		incompleteKey:      true,
+		incompleteFieldKey: "items",
+	}}
+	if !reflect.DeepEqual(content, expected) {
+		t.Errorf("unexpected content %v", content)
+	}
+
+	if content, err = ParseSerializedObjectMinimal(b); err == nil || content != nil {
+		t.Errorf("unexpected content %v without partial results", content)
+	}
+
+	// contents read before an error are not returned without partial results
+	sop = NewSerializedObjectParser(bytes.NewReader(decodeHex(t, streamMagic+streamVersion+tcString+fooEnc+"00")))
+	if content, err = sop.ParseSerializedObjectMinimal(); err == nil || content != nil {
+		t.Errorf("unexpected content %v without partial results", content)
+	}
+}
+
+func TestPartialResultsEveryLength(t *testing.T) {
+	b := objs["hashMapStr"]
+	for n := 0; n < len(b); n++ {
+		sop := NewSerializedObjectParser(bytes.NewReader(b[:n]), SetPartialResults(true))
+		content, err := sop.ParseSerializedObject()
+		// streams ending between top level objects are valid
+		if err != nil && !errors.Is(err, ErrTruncated) {
+			t.Fatalf("%d: unexpected error %+v", n, err)
+		}
+		// the top level array is returned once its class descriptor and size are read
+		if n >= 44 && len(content) == 0 {
+			t.Errorf("%d: expected partial content", n)
+		}
+	}
+}
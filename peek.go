@@ -0,0 +1,216 @@
+package jserial
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoClass is returned by PeekClass if the next top level content has no class, e.g. null or block data.
+var ErrNoClass = errors.New("content has no class")
+
+// ErrNoHandler is returned by Dispatcher.Dispatch if no handler is registered for the class of a message.
+var ErrNoHandler = errors.New("no handler for class")
+
+// ClassInfo describes a class descriptor read by PeekClass.
+type ClassInfo struct {
+	// TypeCode is the type code of the content of the class, e.g. 0x73 for TC_OBJECT or 0x75 for TC_ARRAY.
+	TypeCode byte
+	// Name is the name of the class, e.g. `com.acme.Order` or `[Lcom.acme.Order;`.
+	Name             string
+	SerialVersionUID int64
+	// Flags are the SC_* flags of the class descriptor.
+	Flags  byte
+	Fields []FieldInfo
+	// Super describes the superclass, if it is serializable.
+	Super *ClassInfo
+}
+
+// FieldInfo describes a serializable field of a class.
+type FieldInfo struct {
+	Name string
+	// Type is the type code of the field, e.g. `I` for int, `L` for objects or `[` for arrays.
+	Type string
+	// ClassName is the JVM type signature of object and array fields, e.g. `Ljava/lang/String;`.
+	ClassName string
+}
+
+// peekedContent is a top level content whose class has been read by PeekClass.
+type peekedContent struct {
+	tc      byte
+	offset  int64
+	cls     *clazz
+	info    *ClassInfo
+	resumed bool
+}
+
+// PeekClass reads the stream header and the class descriptor of the first top level content from r without reading
+// the content itself.
+func PeekClass(r io.Reader, options ...Option) (*ClassInfo, error) {
+	return NewSerializedObjectParser(r, options...).PeekClass()
+}
+
+// PeekClass returns the class of the next top level content without decoding its data. Only the class descriptor is
+// read, the content is decoded by the next call to Next or ParseSerializedObject. Strings are reported as
+// `java.lang.String`, for content without a class ErrNoClass is returned.
+func (sop *SerializedObjectParser) PeekClass() (info *ClassInfo, err error) {
+	const (
+		tcObject     = 0x73
+		tcString     = 0x74
+		tcArray      = 0x75
+		tcClass      = 0x76
+		tcLongString = 0x7c
+		tcEnum       = 0x7e
+	)
+
+	if sop.peeked != nil {
+		return sop.peeked.info, nil
+	}
+
+	if err = sop.header(); err != nil {
+		return nil, sop.parseError(err)
+	}
+
+	if err = sop.nextSubStream(); err != nil {
+		return nil, sop.parseError(err)
+	}
+
+	if sop.end() {
+		return nil, io.EOF
+	}
+
+	sop.resetPath()
+
+	var b []byte
+
+	if b, err = sop.rd.Peek(1); err != nil {
+		return nil, sop.parseError(errors.Wrap(err, "error reading type code"))
+	}
+
+	tc := b[0]
+
+	switch tc {
+	case tcString, tcLongString:
+		return &ClassInfo{TypeCode: tc, Name: "java.lang.String"}, nil
+	case tcObject, tcArray, tcClass, tcEnum:
+	default:
+		return nil, sop.parseError(tagf(ErrNoClass, "content with type code %#x has no class", tc))
+	}
+
+	offset := sop.rd.pos
+
+	if _, err = sop.rd.ReadByte(); err != nil {
+		return nil, sop.parseError(errors.Wrap(err, "error reading type code"))
+	}
+
+	sop.typeCodes = append(sop.typeCodes, tc)
+
+	var cls *clazz
+
+	// the class is checked by filters at the depth of the content
+	if isGraphNode(tc) {
+		sop.objDepth++
+	}
+
+	cls, err = sop.classDesc()
+
+	if isGraphNode(tc) {
+		sop.objDepth--
+	}
+
+	if err != nil {
+		return nil, sop.parseError(errors.Wrap(err, "error peeking class"))
+	}
+
+	sop.typeCodes = sop.typeCodes[:len(sop.typeCodes)-1]
+	sop.peeked = &peekedContent{tc: tc, offset: offset, cls: cls}
+
+	if cls == nil {
+		return nil, sop.parseError(tagf(ErrNoClass, "content with type code %#x has a null class", tc))
+	}
+
+	info = newClassInfo(cls)
+	info.TypeCode = tc
+	sop.peeked.info = info
+
+	return
+}
+
+// hasPeeked reports whether the class of the next top level content has been read by PeekClass, so the content
+// continues after its class descriptor.
+func (sop *SerializedObjectParser) hasPeeked() bool {
+	return sop.peeked != nil && !sop.peeked.resumed
+}
+
+// newClassInfo describes cls and its superclasses.
+func newClassInfo(cls *clazz) *ClassInfo {
+	info := &ClassInfo{
+		Name:  cls.name,
+		Flags: cls.flags,
+	}
+
+	if suid, err := strconv.ParseUint(cls.serialVersionUID, 16, 64); err == nil {
+		info.SerialVersionUID = int64(suid)
+	}
+
+	for _, f := range cls.fields {
+		info.Fields = append(info.Fields, FieldInfo{Name: f.name, Type: f.typeName, ClassName: f.className})
+	}
+
+	if cls.super != nil {
+		info.Super = newClassInfo(cls.super)
+	}
+
+	return info
+}
+
+// Handler decodes a message whose class has been peeked, e.g. by calling sop.Next.
+type Handler func(sop *SerializedObjectParser, info *ClassInfo) error
+
+// Dispatcher routes messages to handlers by the class of their first top level content.
+type Dispatcher struct {
+	handlers map[string]Handler
+	fallback Handler
+	options  []Option
+}
+
+// NewDispatcher returns a Dispatcher, the options are applied to the parser of every message.
+func NewDispatcher(options ...Option) *Dispatcher {
+	return &Dispatcher{
+		handlers: map[string]Handler{},
+		options:  options,
+	}
+}
+
+// Handle registers the handler for messages of the class with the given name.
+func (d *Dispatcher) Handle(className string, handler Handler) {
+	d.handlers[className] = handler
+}
+
+// HandleDefault registers the handler for messages of classes without a handler.
+func (d *Dispatcher) HandleDefault(handler Handler) {
+	d.fallback = handler
+}
+
+// Dispatch peeks the class of the message read from r and calls its handler with the parser, which decodes the
+// message. Errors returned by the handler are returned as is.
+func (d *Dispatcher) Dispatch(r io.Reader) error {
+	sop := NewSerializedObjectParser(r, d.options...)
+
+	info, err := sop.PeekClass()
+	if err != nil {
+		return err
+	}
+
+	handler, exists := d.handlers[info.Name]
+	if !exists {
+		if d.fallback == nil {
+			return tagf(ErrNoHandler, "no handler for class '%s'", info.Name)
+		}
+
+		handler = d.fallback
+	}
+
+	return handler(sop, info)
+}
@@ -0,0 +1,138 @@
+package jserial
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPeekClass(t *testing.T) {
+	hexStr := streamHex("", "")
+
+	sop := newTestParser(t, hexStr, SetRecordSpans(true))
+	info, err := sop.PeekClass()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := &ClassInfo{
+		TypeCode:         0x73,
+		Name:             "SomeClass",
+		SerialVersionUID: 0x1234567887654321,
+		Flags:            0x02,
+		Fields:           []FieldInfo{{Name: "foo", Type: "I"}},
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("unexpected class info %+v", info)
+	}
+
+	// peeking again returns the same class
+	if again, _ := sop.PeekClass(); again != info {
+		t.Errorf("unexpected class info %+v", again)
+	}
+
+	// the object is decoded by the same parser
+	content, err := sop.ParseSerializedObjectMinimal()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	full, _ := ParseSerializedObjectMinimal(decodeHex(t, hexStr))
+	if !reflect.DeepEqual(content, full) {
+		t.Errorf("unexpected content %v", content)
+	}
+
+	spans := sop.Spans()
+	if last := spans[len(spans)-1]; last.Offset != 4 || last.Class != "SomeClass" {
+		t.Errorf("unexpected span %+v", last)
+	}
+
+	// object data is not read
+	truncated := strings.TrimSuffix(hexStr, "01234567")
+	if info, err = PeekClass(bytes.NewReader(decodeHex(t, truncated))); err != nil || info.Name != "SomeClass" {
+		t.Errorf("unexpected class info %+v or error %+v", info, err)
+	}
+}
+
+func TestPeekClassFieldless(t *testing.T) {
+	empty := tcObject + tcClassDesc + encodeStr("Empty") + serialVer + scSerializable + "0000" + tcEndBlockData + tcNull
+	hexStr := streamMagic + streamVersion + empty
+
+	// the object has no data following its class descriptor
+	sop := newTestParser(t, hexStr)
+	if _, err := sop.PeekClass(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	content, err := sop.ParseSerializedObjectMinimal()
+	if err != nil || len(content) != 1 || !reflect.DeepEqual(content[0], map[string]interface{}{}) {
+		t.Errorf("unexpected content %v or error %+v", content, err)
+	}
+
+	sop = newTestParser(t, hexStr)
+	if _, err = sop.PeekClass(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if obj, err := sop.ReadObject(); err != nil || obj == nil {
+		t.Errorf("unexpected object %v or error %+v", obj, err)
+	}
+
+	// the header of the next stream is not read before the peeked object
+	sop = newTestParser(t, hexStr+streamMagic+streamVersion+tcString+fooEnc, SetConcatenatedStreams(true))
+	if _, err = sop.PeekClass(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	content, err = sop.ParseSerializedObjectMinimal()
+	if err != nil || len(content) != 2 || content[1] != "foo" {
+		t.Errorf("unexpected content %v or error %+v", content, err)
+	}
+}
+
+func TestPeekClassWithoutClass(t *testing.T) {
+	info, err := PeekClass(bytes.NewReader(decodeHex(t, streamMagic+streamVersion+tcString+fooEnc)))
+	if err != nil || info.Name != "java.lang.String" {
+		t.Errorf("unexpected class info %+v or error %+v", info, err)
+	}
+
+	_, err = PeekClass(bytes.NewReader(decodeHex(t, streamMagic+streamVersion+tcNull)))
+	if !errors.Is(err, ErrNoClass) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	_, err = PeekClass(bytes.NewReader(decodeHex(t, streamMagic+streamVersion+tcObject+tcNull)))
+	if !errors.Is(err, ErrNoClass) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	var decoded []interface{}
+
+	d := NewDispatcher()
+	d.Handle("SomeClass", func(sop *SerializedObjectParser, info *ClassInfo) (err error) {
+		decoded, err = sop.ParseSerializedObjectMinimal()
+		return
+	})
+
+	if err := d.Dispatch(bytes.NewReader(decodeHex(t, streamHex("", "")))); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(decoded) != 1 {
+		t.Errorf("unexpected content %v", decoded)
+	}
+
+	str := decodeHex(t, streamMagic+streamVersion+tcString+fooEnc)
+	if err := d.Dispatch(bytes.NewReader(str)); !errors.Is(err, ErrNoHandler) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	d.HandleDefault(func(sop *SerializedObjectParser, info *ClassInfo) (err error) {
+		decoded, err = sop.ParseSerializedObjectMinimal()
+		return
+	})
+
+	if err := d.Dispatch(bytes.NewReader(str)); err != nil || !reflect.DeepEqual(decoded, []interface{}{"foo"}) {
+		t.Errorf("unexpected content %v or error %+v", decoded, err)
+	}
+}
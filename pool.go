@@ -0,0 +1,94 @@
+package jserial
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// parserPool holds parsers with the default options used by the package level parse functions.
+var parserPool = sync.Pool{
+	New: func() interface{} {
+		return NewSerializedObjectParserBytes(nil)
+	},
+}
+
+// Reset discards the parser's state and makes it read a new stream from r, keeping its options. Internal buffers and
+// the handle table are reused, so resetting a parser allocates less than creating a new one. Values returned by
+// Spans, SubStreams and Warnings before the reset are not modified.
+func (sop *SerializedObjectParser) Reset(r io.Reader) {
+	sop.reset()
+
+	switch br, isBufio := sop.rd.br.(*bufio.Reader); {
+	case sop.exactReads:
+		sop.rd.br = newExactSource(r)
+	case isBufio:
+		br.Reset(r)
+	default:
+		sop.rd.br = bufio.NewReaderSize(r, sop.bufferSize)
+	}
+
+	sop.zeroCopy = false
+	sop.initSeeker(r)
+}
+
+// ResetBytes is like Reset but makes the parser read from buf without copying, like a parser created by
+// NewSerializedObjectParserBytes.
+func (sop *SerializedObjectParser) ResetBytes(buf []byte) {
+	sop.resetSlice(buf)
+	sop.zeroCopy = true
+}
+
+// resetSlice discards the parser's state and makes it read from buf.
+func (sop *SerializedObjectParser) resetSlice(buf []byte) {
+	sop.reset()
+
+	src, isSlice := sop.rd.br.(*sliceSource)
+	if !isSlice {
+		src = &sliceSource{}
+		sop.rd.br = src
+	}
+
+	src.buf, src.off = buf, 0
+
+	// a slice is never read through a seeker
+	sop.seeker, sop.seekBase = nil, 0
+}
+
+// reset discards the state of the stream being read.
+func (sop *SerializedObjectParser) reset() {
+	// clear the handles so the pool does not keep the parsed values alive
+	for i := range sop.handles {
+		sop.handles[i] = nil
+	}
+
+	sop.buf.Reset()
+	sop.rd.pos, sop.rd.eof = 0, false
+	sop.handles = sop.handles[:0]
+	sop.headerRead = sop.skipHeader
+	sop.block = nil
+	sop.depth, sop.objDepth, sop.refs = 0, 0, 0
+	sop.limits.allocated = 0
+	sop.resetPath()
+	sop.ctx, sop.ctxTicks = nil, 0
+	sop.warnings, sop.softErr = nil, nil
+	sop.pending, sop.hasPending, sop.stopped, sop.resynced = nil, false, false, false
+	sop.spans, sop.openSpan, sop.lastSpan, sop.spanIndex = nil, -1, -1, nil
+	sop.subStream, sop.subStreams = 0, nil
+	sop.contentOffset, sop.peeked = 0, nil
+}
+
+// acquireParser returns a pooled parser with the default options reading a copy of the values in buf.
+func acquireParser(buf []byte) *SerializedObjectParser {
+	sop := parserPool.Get().(*SerializedObjectParser)
+	sop.resetSlice(buf)
+	sop.zeroCopy = false
+
+	return sop
+}
+
+// releaseParser returns a parser obtained from acquireParser to the pool.
+func releaseParser(sop *SerializedObjectParser) {
+	sop.resetSlice(nil)
+	parserPool.Put(sop)
+}
@@ -0,0 +1,155 @@
+package jserial
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	sop := NewSerializedObjectParser(bytes.NewReader(objs["dupe"]))
+	if _, err := sop.ParseSerializedObject(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	handles := sop.handles
+
+	sop.Reset(bytes.NewReader(objs["canary"]))
+	if len(sop.handles) != 0 || handles[0] != nil {
+		t.Error("handles must be cleared")
+	}
+
+	content, err := sop.ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	expected, _ := ParseSerializedObject(objs["canary"])
+	if !reflect.DeepEqual(content, expected) {
+		t.Errorf("unexpected content %v", content)
+	}
+	if &sop.handles[0] != &handles[0] {
+		t.Error("handle table must be reused")
+	}
+
+	// reading the new stream starts at offset zero
+	if sop.rd.pos != int64(len(objs["canary"])) {
+		t.Errorf("unexpected position %d", sop.rd.pos)
+	}
+}
+
+func TestResetKeepsOptions(t *testing.T) {
+	fragment := decodeHex(t, tcString+fooEnc)
+
+	sop := NewSerializedObjectParser(bytes.NewReader(fragment), SetSkipHeader(true), SetRecordSpans(true))
+	if _, err := sop.ParseSerializedObject(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	spans := sop.Spans()
+
+	sop.Reset(bytes.NewReader(fragment))
+	content, err := sop.ParseSerializedObject()
+	if err != nil || !reflect.DeepEqual(content, []interface{}{"foo"}) {
+		t.Errorf("unexpected content %v or error %+v", content, err)
+	}
+	if len(spans) != 1 || len(sop.Spans()) != 1 || &spans[0] == &sop.Spans()[0] {
+		t.Error("spans must be recorded again")
+	}
+}
+
+func TestResetBytes(t *testing.T) {
+	sop := NewSerializedObjectParser(bytes.NewReader(objs["canary"]))
+
+	buf := decodeHex(t, streamMagic+streamVersion+tcBlockData+"03616263")
+	sop.ResetBytes(buf)
+
+	content, err := sop.ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if block := content[0].([]byte); &block[0] != &buf[6] {
+		t.Error("block data must share memory with the input")
+	}
+
+	// resetting to a reader switches back to copying
+	sop.Reset(bytes.NewReader(buf))
+	if content, err = sop.ParseSerializedObject(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if block := content[0].([]byte); &block[0] == &buf[6] {
+		t.Error("block data must be copied")
+	}
+}
+
+func TestPooledParsing(t *testing.T) {
+	expected := map[string][]interface{}{}
+	for name, buf := range objs {
+		content, err := NewSerializedObjectParser(bytes.NewReader(buf), SetMaxDataBlockSize(len(buf))).
+			ParseSerializedObject()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %+v", name, err)
+		}
+		expected[name] = content
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for name, buf := range objs {
+				content, err := ParseSerializedObject(buf)
+				if err != nil {
+					t.Errorf("%s: unexpected error: %+v", name, err)
+				} else if !reflect.DeepEqual(content, expected[name]) {
+					t.Errorf("%s: unexpected content %v", name, content)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func BenchmarkParserReuse(b *testing.B) {
+	buf := objs["canary"]
+
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			sop := NewSerializedObjectParser(bytes.NewReader(buf), SetMaxDataBlockSize(len(buf)))
+			if _, err := sop.ParseSerializedObject(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reset", func(b *testing.B) {
+		b.ReportAllocs()
+
+		rd := bytes.NewReader(buf)
+		sop := NewSerializedObjectParser(rd)
+
+		for i := 0; i < b.N; i++ {
+			rd.Reset(buf)
+			sop.Reset(rd)
+
+			if _, err := sop.ParseSerializedObject(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := ParseSerializedObject(buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
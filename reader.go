@@ -0,0 +1,115 @@
+package jserial
+
+import (
+	"io"
+	"io/ioutil"
+)
+
+// historySize is the number of recently read bytes kept to describe the position of errors.
+const historySize = 32
+
+// reader wraps the byteSource used by the parser and keeps track of the number of bytes consumed from the stream.
+// Only the methods needed by the parser are exposed so no read can bypass the position bookkeeping.
+type reader struct {
+	br   byteSource
+	pos  int64
+	hist [historySize]byte
+	eof  bool
+	// capture collects the bytes consumed while capturing is set.
+	capture   []byte
+	capturing bool
+}
+
+func (r *reader) Read(p []byte) (n int, err error) {
+	n, err = r.br.Read(p)
+	r.eof = r.eof || err == io.EOF
+
+	r.record(p[:n])
+
+	return
+}
+
+// record advances the position past the consumed bytes p and adds them to the history.
+func (r *reader) record(p []byte) {
+	if r.capturing {
+		r.capture = append(r.capture, p...)
+	}
+
+	// only the tail of p ends up in the history
+	skip := 0
+	if len(p) > historySize {
+		skip = len(p) - historySize
+	}
+
+	r.pos += int64(skip)
+
+	for _, b := range p[skip:] {
+		r.hist[r.pos%historySize] = b
+		r.pos++
+	}
+}
+
+func (r *reader) ReadByte() (b byte, err error) {
+	if b, err = r.br.ReadByte(); err == nil {
+		r.hist[r.pos%historySize] = b
+		r.pos++
+
+		if r.capturing {
+			r.capture = append(r.capture, b)
+		}
+	} else if err == io.EOF {
+		r.eof = true
+	}
+
+	return
+}
+
+func (r *reader) UnreadByte() (err error) {
+	if err = r.br.UnreadByte(); err == nil {
+		r.pos--
+
+		if r.capturing && len(r.capture) > 0 {
+			r.capture = r.capture[:len(r.capture)-1]
+		}
+	}
+
+	return
+}
+
+// discard consumes the bytes just returned by Peek.
+func (r *reader) discard(peeked []byte) (err error) {
+	discarder, isDiscarder := r.br.(interface{ Discard(n int) (int, error) })
+	if !isDiscarder {
+		_, err = io.CopyN(ioutil.Discard, r, int64(len(peeked)))
+
+		return
+	}
+
+	r.record(peeked)
+	_, err = discarder.Discard(len(peeked))
+
+	return
+}
+
+// Peek returns the next n bytes without consuming them.
+func (r *reader) Peek(n int) ([]byte, error) {
+	return r.br.Peek(n)
+}
+
+// Buffered returns the number of bytes that can be read from the current buffer.
+func (r *reader) Buffered() int {
+	return r.br.Buffered()
+}
+
+// history returns the most recently read bytes and the position of the first of them.
+func (r *reader) history() (b []byte, offset int64) {
+	if offset = r.pos - historySize; offset < 0 {
+		offset = 0
+	}
+
+	for i := offset; i < r.pos; i++ {
+		b = append(b, r.hist[i%historySize])
+	}
+
+	return
+}
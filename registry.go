@@ -0,0 +1,270 @@
+package jserial
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Registry maps class patterns to PostProc implementations.
+//
+// A pattern is one of:
+//   - `name@suid` which matches a class by name and hex serialVersionUID (e.g. `java.util.Date@686a81014b597419`)
+//   - `name` which matches a class by name regardless of serialVersionUID
+//   - a glob (see path.Match) which matches class names (e.g. `com.acme.*`)
+//
+// The leading brackets of array class names (e.g. `[B` or `[Lcom.acme.Order;`) are literal, so array classes can be
+// matched by name and globs such as `[Lcom.acme.*` match arrays.
+//
+// When several patterns match the same class the most specific one wins: exact matches take precedence over name
+// matches which take precedence over globs (checked in registration order). Patterns added with RegisterSuperclass
+// are only consulted when nothing matches the class itself, starting with the nearest superclass.
+//
+// The zero value is an empty registry ready for use. A Registry is safe for concurrent use: Register and Unregister
+// copy the underlying tables so parsers never observe a partially updated registry.
+type Registry struct {
+	mu    sync.Mutex
+	table atomic.Value // *registryTable
+}
+
+// NewRegistry returns a Registry populated with the built-in PostProc implementations.
+func NewRegistry() *Registry {
+	registry := &Registry{}
+	table := &registryTable{}
+
+	for pattern, proc := range defaultPostProcs() {
+		table.self.add(pattern, proc)
+	}
+
+	registry.table.Store(table)
+
+	return registry
+}
+
+// Clone returns a copy of the registry which can be modified independently.
+func (r *Registry) Clone() *Registry {
+	clone := &Registry{}
+	clone.table.Store(r.load().clone())
+
+	return clone
+}
+
+// Register adds (or replaces) the PostProc used for classes matching pattern.
+func (r *Registry) Register(pattern string, proc PostProc) error {
+	return r.update(pattern, func(t *registryTable) {
+		t.self.add(pattern, proc)
+	})
+}
+
+// RegisterSuperclass adds (or replaces) the PostProc used for classes with a superclass matching pattern.
+func (r *Registry) RegisterSuperclass(pattern string, proc PostProc) error {
+	return r.update(pattern, func(t *registryTable) {
+		t.super.add(pattern, proc)
+	})
+}
+
+// Unregister removes the PostProc previously registered for pattern.
+func (r *Registry) Unregister(pattern string) {
+	_ = r.update(pattern, func(t *registryTable) {
+		t.self.remove(pattern)
+	})
+}
+
+// UnregisterSuperclass removes the PostProc previously registered for pattern with RegisterSuperclass.
+func (r *Registry) UnregisterSuperclass(pattern string) {
+	_ = r.update(pattern, func(t *registryTable) {
+		t.super.remove(pattern)
+	})
+}
+
+// update validates pattern then applies fn to a copy of the current table before publishing it.
+func (r *Registry) update(pattern string, fn func(t *registryTable)) error {
+	if isGlob(pattern) {
+		if _, err := path.Match(globExpr(pattern), ""); err != nil {
+			return errors.Wrapf(err, "invalid pattern '%s'", pattern)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	table := r.load().clone()
+	fn(table)
+	r.table.Store(table)
+
+	return nil
+}
+
+// load returns the current table which must not be modified.
+func (r *Registry) load() *registryTable {
+	if table, isTable := r.table.Load().(*registryTable); isTable {
+		return table
+	}
+
+	return &registryTable{}
+}
+
+// lookup returns the PostProc registered for cls or nil if there is none. The result is memoized for classes interned
+// by a DescriptorCache until the registry changes.
+func (r *Registry) lookup(cls *clazz) PostProc {
+	table := r.load()
+
+	if cls.memo == nil {
+		return table.lookup(cls)
+	}
+
+	if memo, isMemo := cls.memo.Load().(*procMemo); isMemo && memo.table == table {
+		return memo.proc
+	}
+
+	proc := table.lookup(cls)
+	cls.memo.Store(&procMemo{table: table, proc: proc})
+
+	return proc
+}
+
+// registryTable is an immutable snapshot of a Registry.
+type registryTable struct {
+	self  ruleSet
+	super ruleSet
+}
+
+func (t *registryTable) clone() *registryTable {
+	return &registryTable{
+		self:  t.self.clone(),
+		super: t.super.clone(),
+	}
+}
+
+func (t *registryTable) lookup(cls *clazz) PostProc {
+	if proc := t.self.match(cls); proc != nil {
+		return proc
+	}
+
+	if t.super.empty() {
+		return nil
+	}
+
+	seen := map[*clazz]bool{cls: true}
+
+	for super := cls.super; super != nil && !seen[super]; super = super.super {
+		if proc := t.super.match(super); proc != nil {
+			return proc
+		}
+
+		seen[super] = true
+	}
+
+	return nil
+}
+
+// globRule associates a glob pattern with a PostProc.
+type globRule struct {
+	pattern string
+	expr    string
+	proc    PostProc
+}
+
+// ruleSet holds the patterns registered for one kind of match.
+type ruleSet struct {
+	exact  map[string]PostProc
+	byName map[string]PostProc
+	globs  []globRule
+}
+
+func (rs *ruleSet) empty() bool {
+	return len(rs.exact) == 0 && len(rs.byName) == 0 && len(rs.globs) == 0
+}
+
+func (rs *ruleSet) clone() ruleSet {
+	clone := ruleSet{
+		exact:  make(map[string]PostProc, len(rs.exact)),
+		byName: make(map[string]PostProc, len(rs.byName)),
+		globs:  make([]globRule, len(rs.globs)),
+	}
+
+	for k, v := range rs.exact {
+		clone.exact[k] = v
+	}
+
+	for k, v := range rs.byName {
+		clone.byName[k] = v
+	}
+
+	copy(clone.globs, rs.globs)
+
+	return clone
+}
+
+func (rs *ruleSet) add(pattern string, proc PostProc) {
+	switch {
+	case isGlob(pattern):
+		for idx, rule := range rs.globs {
+			if rule.pattern == pattern {
+				rs.globs[idx].proc = proc
+
+				return
+			}
+		}
+
+		rs.globs = append(rs.globs, globRule{pattern: pattern, expr: globExpr(pattern), proc: proc})
+	case strings.Contains(pattern, "@"):
+		if rs.exact == nil {
+			rs.exact = make(map[string]PostProc)
+		}
+
+		rs.exact[pattern] = proc
+	default:
+		if rs.byName == nil {
+			rs.byName = make(map[string]PostProc)
+		}
+
+		rs.byName[pattern] = proc
+	}
+}
+
+func (rs *ruleSet) remove(pattern string) {
+	delete(rs.exact, pattern)
+	delete(rs.byName, pattern)
+
+	for idx, rule := range rs.globs {
+		if rule.pattern == pattern {
+			rs.globs = append(rs.globs[:idx], rs.globs[idx+1:]...)
+
+			return
+		}
+	}
+}
+
+func (rs *ruleSet) match(cls *clazz) PostProc {
+	if proc, exists := rs.exact[cls.signature()]; exists {
+		return proc
+	}
+
+	if proc, exists := rs.byName[cls.name]; exists {
+		return proc
+	}
+
+	for _, rule := range rs.globs {
+		if matched, _ := path.Match(rule.expr, cls.name); matched {
+			return rule.proc
+		}
+	}
+
+	return nil
+}
+
+// isGlob reports whether pattern contains any glob meta characters after the leading brackets of an array class name.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(strings.TrimLeft(pattern, "["), "*?[\\")
+}
+
+// globExpr returns the path.Match expression of the glob pattern, escaping the leading brackets of array class names.
+func globExpr(pattern string) string {
+	name := strings.TrimLeft(pattern, "[")
+
+	return strings.Repeat(`\[`, len(pattern)-len(name)) + name
+}
@@ -0,0 +1,162 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func valuePostProc(val interface{}) PostProc {
+	return func(r *BlockDataReader) (map[string]interface{}, error) {
+		fields := r.DefaultFields()
+		fields["value"] = val
+		return fields, nil
+	}
+}
+
+func parseWithRegistry(t *testing.T, b []byte, registry *Registry) []interface{} {
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetMaxDataBlockSize(len(b)), WithPostProcs(registry))
+	obj, err := sop.ParseSerializedObjectMinimal()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	return obj
+}
+
+func TestRegistryPatterns(t *testing.T) {
+	registry := &Registry{}
+	if err := registry.Register("java.util.*", valuePostProc("glob")); err != nil {
+		t.Fatal(err)
+	}
+	if parseWithRegistry(t, objs["date"], registry)[1] != "glob" {
+		t.Fail()
+	}
+	if err := registry.Register("java.util.Date", valuePostProc("name")); err != nil {
+		t.Fatal(err)
+	}
+	if parseWithRegistry(t, objs["date"], registry)[1] != "name" {
+		t.Fail()
+	}
+	if err := registry.Register("java.util.Date@686a81014b597419", valuePostProc("exact")); err != nil {
+		t.Fatal(err)
+	}
+	if parseWithRegistry(t, objs["date"], registry)[1] != "exact" {
+		t.Fail()
+	}
+	if err := registry.Register("java.util.Date@0000000000000000", valuePostProc("other suid")); err != nil {
+		t.Fatal(err)
+	}
+	if parseWithRegistry(t, objs["date"], registry)[1] != "exact" {
+		t.Fail()
+	}
+	registry.Unregister("java.util.Date@686a81014b597419")
+	if parseWithRegistry(t, objs["date"], registry)[1] != "name" {
+		t.Fail()
+	}
+}
+
+func TestRegistryBadPattern(t *testing.T) {
+	if err := (&Registry{}).Register("java.util.[", valuePostProc(nil)); err == nil {
+		t.Fail()
+	}
+}
+
+func TestRegistrySuperclass(t *testing.T) {
+	b, _ := hex.DecodeString(streamPrefix + tcClassDesc + encodeStr("Sub") + serialVer + "03" + "0000" + tcEndBlockData +
+		tcClassDesc + encodeStr("Base") + serialVer + scSerializable + "0000" + tcEndBlockData + tcNull +
+		tcBlockData + "04" + "0000007b" + tcEndBlockData)
+
+	registry := &Registry{}
+	if err := registry.RegisterSuperclass("Base", valuePostProc("super")); err != nil {
+		t.Fatal(err)
+	}
+	if parseWithRegistry(t, b, registry)[0] != "super" {
+		t.Fail()
+	}
+	if err := registry.RegisterSuperclass("Sub", valuePostProc("self")); err != nil {
+		t.Fatal(err)
+	}
+	if parseWithRegistry(t, b, registry)[0] != "super" {
+		t.Fail()
+	}
+	if err := registry.Register("S*", valuePostProc("glob")); err != nil {
+		t.Fatal(err)
+	}
+	if parseWithRegistry(t, b, registry)[0] != "glob" {
+		t.Fail()
+	}
+	registry.Unregister("S*")
+	registry.UnregisterSuperclass("Base")
+	if _, isMap := parseWithRegistry(t, b, registry)[0].(map[string]interface{}); !isMap {
+		t.Fail()
+	}
+}
+
+func TestRegistryCopyOnWrite(t *testing.T) {
+	registry := NewRegistry()
+	clone := registry.Clone()
+	clone.Unregister("java.util.Date@686a81014b597419")
+
+	if _, isMap := parseWithRegistry(t, objs["date"], clone)[1].(map[string]interface{}); !isMap {
+		t.Fail()
+	}
+	if reflect.DeepEqual(parseWithRegistry(t, objs["date"], registry)[1], parseWithRegistry(t, objs["date"], clone)[1]) {
+		t.Fail()
+	}
+}
+
+func TestRegistryEmpty(t *testing.T) {
+	obj := parseWithRegistry(t, objs["hashMapStr"], &Registry{})
+	m, isMap := obj[1].(map[string]interface{})
+	if !isMap {
+		t.Fail()
+	}
+	if _, hasAnnotations := m["@"]; !hasAnnotations {
+		t.Fail()
+	}
+}
+
+func TestRegistryArrayClasses(t *testing.T) {
+	registry := &Registry{}
+	for pattern, val := range map[string]string{
+		"[B":                                "bytes",
+		"[Ljava.lang.String;":               "strings",
+		"[[Ljava.lang.String;@" + serialVer: "exact",
+		"[Lcom.acme.*":                      "glob",
+	} {
+		if err := registry.Register(pattern, valuePostProc(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, expected := range map[string]string{
+		"[B":                   "bytes",
+		"[Ljava.lang.String;":  "strings",
+		"[[Ljava.lang.String;": "exact",
+		"[Lcom.acme.Order;":    "glob",
+		"B":                    "",
+		"[Ljava.lang.Object;":  "",
+	} {
+		proc := registry.lookup(&clazz{name: name, serialVersionUID: serialVer})
+		if expected == "" {
+			if proc != nil {
+				t.Errorf("%s: unexpected post processor", name)
+			}
+			continue
+		}
+		if proc == nil {
+			t.Errorf("%s: no post processor", name)
+			continue
+		}
+		fields, err := proc(&BlockDataReader{fields: map[string]interface{}{}})
+		if err != nil || fields["value"] != expected {
+			t.Errorf("%s: unexpected value %v instead of %s", name, fields["value"], expected)
+		}
+	}
+
+	// array patterns are looked up by name rather than as globs
+	if table := registry.load(); len(table.self.globs) != 1 || len(table.self.byName) != 2 || len(table.self.exact) != 1 {
+		t.Errorf("unexpected tables %+v", table.self)
+	}
+}
@@ -0,0 +1,330 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Severity ranks how dangerous a GadgetRule match is.
+type Severity int
+
+// Severities of GadgetRule matches, from least to most dangerous.
+const (
+	SeverityLow Severity = iota + 1
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// GadgetRule describes a class or combination of classes known to be used in deserialization attacks.
+type GadgetRule struct {
+	// Name identifies the rule in findings.
+	Name string
+	// Severity of a match.
+	Severity Severity
+	// Classes are class patterns in SerialFilter syntax (e.g. `org.example.Evil`, `org.example.*` or
+	// `java.base/java.net.URL`). The rule matches when every pattern matches a class found in the stream.
+	Classes []string
+}
+
+// DefaultGadgetRules returns a copy of the built-in rules, which can be extended and passed to NewScanner.
+func DefaultGadgetRules() []GadgetRule {
+	const (
+		cc  = "org.apache.commons.collections.functors."
+		cc4 = "org.apache.commons.collections4.functors."
+	)
+
+	return []GadgetRule{
+		{"commons-collections-invoker", SeverityCritical, []string{cc + "InvokerTransformer"}},
+		{"commons-collections4-invoker", SeverityCritical, []string{cc4 + "InvokerTransformer"}},
+		{"commons-collections-instantiate", SeverityCritical, []string{cc + "InstantiateTransformer"}},
+		{"commons-collections4-instantiate", SeverityCritical, []string{cc4 + "InstantiateTransformer"}},
+		{"commons-collections-chain", SeverityCritical, []string{cc + "ChainedTransformer", cc + "ConstantTransformer"}},
+		{"commons-collections4-chain", SeverityCritical, []string{cc4 + "ChainedTransformer", cc4 + "ConstantTransformer"}},
+		{"xalan-templates", SeverityCritical, []string{"com.sun.org.apache.xalan.internal.xsltc.trax.TemplatesImpl"}},
+		{"xalan-templates-standalone", SeverityCritical, []string{"org.apache.xalan.xsltc.trax.TemplatesImpl"}},
+		{"commons-beanutils-templates", SeverityCritical, []string{
+			"org.apache.commons.beanutils.BeanComparator",
+			"com.sun.org.apache.xalan.internal.xsltc.trax.TemplatesImpl",
+		}},
+		{"commons-beanutils-comparator", SeverityMedium, []string{"org.apache.commons.beanutils.BeanComparator"}},
+		{"annotation-invocation-handler", SeverityHigh, []string{"sun.reflect.annotation.AnnotationInvocationHandler"}},
+		{"spring-method-invoke-type-provider", SeverityCritical, []string{
+			"org.springframework.core.SerializableTypeWrapper$MethodInvokeTypeProvider",
+		}},
+		{"spring-object-factory-handler", SeverityHigh, []string{
+			"org.springframework.beans.factory.support.AutowireUtils$ObjectFactoryDelegatingInvocationHandler",
+		}},
+		{"jndi-reference", SeverityHigh, []string{"javax.naming.Reference"}},
+		{"jndi-reference-wrapper", SeverityHigh, []string{"com.sun.jndi.rmi.registry.ReferenceWrapper"}},
+		{"rmi-remote-object-handler", SeverityHigh, []string{"java.rmi.server.RemoteObjectInvocationHandler"}},
+		{"groovy-method-closure", SeverityCritical, []string{"org.codehaus.groovy.runtime.MethodClosure"}},
+		{"groovy-converted-closure", SeverityHigh, []string{"org.codehaus.groovy.runtime.ConvertedClosure"}},
+		{"rome-object-bean", SeverityHigh, []string{"com.sun.syndication.feed.impl.ObjectBean"}},
+		{"c3p0-pool-backed-data-source", SeverityHigh, []string{
+			"com.mchange.v2.c3p0.impl.PoolBackedDataSourceBase",
+		}},
+		{"url-dns-probe", SeverityLow, []string{"java.util.HashMap", "java.net.URL"}},
+	}
+}
+
+// Finding is a GadgetRule matched by a stream.
+type Finding struct {
+	// Rule is the name of the matched rule.
+	Rule string
+	// Severity of the matched rule.
+	Severity Severity
+	// Class is the name of the class which completed the match.
+	Class string
+	// Path lists the classes of the objects and arrays enclosing Class, from the outermost object to Class itself.
+	// It only contains Class if the descriptor was found by sweeping input the parser could not read.
+	Path []string
+	// Offset is the position of Class's descriptor in the stream.
+	Offset int64
+}
+
+// Scanner reports GadgetRule matches in serialized streams. It only reads class descriptors and object data, nothing
+// in the stream is ever instantiated or executed.
+type Scanner struct {
+	rules   []scanRule
+	options []Option
+}
+
+type scanRule struct {
+	GadgetRule
+	patterns []filterRule
+}
+
+// classSighting is a class descriptor found in a stream.
+type classSighting struct {
+	name   string
+	offset int64
+	path   []string
+}
+
+// NewScanner returns a Scanner for rules, which defaults to DefaultGadgetRules when empty. The options are applied
+// to the parser used to read streams, e.g. to set resource limits.
+func NewScanner(rules []GadgetRule, options ...Option) (*Scanner, error) {
+	if len(rules) == 0 {
+		rules = DefaultGadgetRules()
+	}
+
+	s := &Scanner{options: options}
+
+	for _, rule := range rules {
+		if len(rule.Classes) == 0 {
+			return nil, errors.Errorf("gadget rule '%s' has no classes", rule.Name)
+		}
+
+		sr := scanRule{GadgetRule: rule}
+
+		for _, pattern := range rule.Classes {
+			if strings.HasPrefix(pattern, "!") {
+				return nil, errors.Errorf("invalid class pattern '%s' in gadget rule '%s'", pattern, rule.Name)
+			}
+
+			fr, err := parseFilterRule(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid gadget rule '%s'", rule.Name)
+			}
+
+			sr.patterns = append(sr.patterns, fr)
+		}
+
+		s.rules = append(s.rules, sr)
+	}
+
+	return s, nil
+}
+
+// ScanGadgets scans b with the default rules.
+func ScanGadgets(b []byte) ([]Finding, error) {
+	s, err := NewScanner(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Scan(b)
+}
+
+// ScanReader reads all of r and scans it.
+func (s *Scanner) ScanReader(r io.Reader) ([]Finding, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading stream")
+	}
+
+	return s.Scan(b)
+}
+
+// Scan returns the rules matched by the serialized stream b, ordered by offset. Streams the parser cannot read
+// completely (e.g. because they contain dynamic proxies) are swept for class descriptors instead, so findings are
+// returned along with the error describing why parsing failed.
+func (s *Scanner) Scan(b []byte) (findings []Finding, err error) {
+	var sightings []classSighting
+
+	seen := map[int64]bool{}
+
+	options := append([]Option{SetMaxStringLength(len(b)), SetMaxBlockDataSize(len(b)), WithPostProcs(&Registry{})},
+		s.options...)
+	sop := NewSerializedObjectParser(bytes.NewReader(b), options...)
+	sop.classHook = func(name string, offset int64) {
+		sightings = append(sightings, classSighting{name: name, offset: offset, path: append(sop.classPath(), name)})
+		seen[offset] = true
+	}
+
+	if _, err = sop.ParseSerializedObject(); err != nil {
+		err = errors.Wrap(err, "error parsing stream, findings are based on a sweep of the input")
+
+		sweepClassDescs(b, func(name string, offset int64) {
+			if !seen[offset] {
+				sightings = append(sightings, classSighting{name: name, offset: offset, path: []string{name}})
+			}
+		})
+
+		sort.Slice(sightings, func(i, j int) bool {
+			return sightings[i].offset < sightings[j].offset
+		})
+	}
+
+	for i := range s.rules {
+		if finding, matched := s.rules[i].match(sightings); matched {
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Offset < findings[j].Offset
+	})
+
+	return
+}
+
+// match returns a Finding for the sighting completing the rule, sightings must be ordered by offset.
+func (sr *scanRule) match(sightings []classSighting) (finding Finding, matched bool) {
+	found := make([]bool, len(sr.patterns))
+	remaining := len(sr.patterns)
+
+	for _, sighting := range sightings {
+		name := sighting.name
+		if strings.HasPrefix(name, "[") {
+			if name = arrayElementClass(name); name == "" {
+				continue
+			}
+		}
+
+		for i := range sr.patterns {
+			if !found[i] && sr.patterns[i].matches(name) {
+				found[i] = true
+				remaining--
+			}
+		}
+
+		if remaining == 0 {
+			return Finding{
+				Rule:     sr.Name,
+				Severity: sr.Severity,
+				Class:    sighting.name,
+				Path:     sighting.path,
+				Offset:   sighting.offset,
+			}, true
+		}
+	}
+
+	return
+}
+
+// sweepClassDescs calls fn for every byte sequence in b which looks like a class or proxy class descriptor.
+func sweepClassDescs(b []byte, fn func(name string, offset int64)) {
+	const (
+		tcClassDesc      = 0x72
+		tcProxyClassDesc = 0x7d
+		maxInterfaces    = 65535
+	)
+
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case tcClassDesc:
+			// name, serialVersionUID and flags
+			if name, n := sweepClassName(b[i+1:]); n > 0 && i+1+n+9 <= len(b) && b[i+1+n+8]&^0x1f == 0 {
+				fn(name, int64(i))
+			}
+		case tcProxyClassDesc:
+			if i+5 > len(b) {
+				continue
+			}
+
+			cnt := binary.BigEndian.Uint32(b[i+1:])
+			if cnt == 0 || cnt > maxInterfaces {
+				continue
+			}
+
+			var names []string
+
+			for j, off := uint32(0), i+5; j < cnt; j++ {
+				name, n := sweepClassName(b[off:])
+				if n == 0 {
+					names = nil
+
+					break
+				}
+
+				names = append(names, name)
+				off += n
+			}
+
+			for _, name := range names {
+				fn(name, int64(i))
+			}
+		}
+	}
+}
+
+// sweepClassName reads a length prefixed class name from b and returns it and the number of bytes read, which is zero
+// if b does not start with a valid class name.
+func sweepClassName(b []byte) (string, int) {
+	const minClassNameLength = 2
+
+	if len(b) < 2 {
+		return "", 0
+	}
+
+	size := int(binary.BigEndian.Uint16(b))
+	if size < minClassNameLength || len(b) < 2+size {
+		return "", 0
+	}
+
+	name := b[2 : 2+size]
+
+	for _, c := range name {
+		isLetter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		isDigit := c >= '0' && c <= '9'
+
+		if !isLetter && !isDigit && !strings.ContainsRune("._$[;", rune(c)) {
+			return "", 0
+		}
+	}
+
+	return string(name), 2 + size
+}
@@ -0,0 +1,115 @@
+package jserial
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+const ccFunctors = "org.apache.commons.collections.functors."
+
+// emptyObjectHex returns an object of a serializable class without fields or super class.
+func emptyObjectHex(class string) string {
+	return tcObject + tcClassDesc + encodeStr(class) + serialVer + scSerializable + "0000" + tcEndBlockData + tcNull
+}
+
+func objectArrayHex(elements ...string) string {
+	res := tcArray + tcClassDesc + encodeStr("[Ljava.lang.Object;") + serialVer + scSerializable + "0000" +
+		tcEndBlockData + tcNull + fmt.Sprintf("%08x", len(elements))
+	for _, element := range elements {
+		res += element
+	}
+	return res
+}
+
+func scanHex(t *testing.T, rules []GadgetRule, hexStr string) ([]Finding, error) {
+	b, err := hex.DecodeString(hexStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScanner(rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	return s.Scan(b)
+}
+
+func findingRules(findings []Finding) (rules []string) {
+	for _, finding := range findings {
+		rules = append(rules, finding.Rule)
+	}
+	return
+}
+
+func TestScanCommonsCollections(t *testing.T) {
+	findings, err := scanHex(t, nil, streamMagic+streamVersion+objectArrayHex(
+		emptyObjectHex(ccFunctors+"ChainedTransformer"),
+		emptyObjectHex(ccFunctors+"ConstantTransformer"),
+		emptyObjectHex(ccFunctors+"InvokerTransformer"),
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !reflect.DeepEqual(findingRules(findings), []string{"commons-collections-chain", "commons-collections-invoker"}) {
+		t.Fatalf("unexpected findings %v", findings)
+	}
+
+	invoker := findings[1]
+	if invoker.Severity != SeverityCritical || invoker.Class != ccFunctors+"InvokerTransformer" {
+		t.Errorf("unexpected finding %v", invoker)
+	}
+	if !reflect.DeepEqual(invoker.Path, []string{"[Ljava.lang.Object;", ccFunctors + "InvokerTransformer"}) {
+		t.Errorf("unexpected path %v", invoker.Path)
+	}
+	// header, array, two objects and the type code of the third object precede the descriptor
+	offset := (len(streamMagic+streamVersion+objectArrayHex()+
+		emptyObjectHex(ccFunctors+"ChainedTransformer")+emptyObjectHex(ccFunctors+"ConstantTransformer")) + 2) / 2
+	if invoker.Offset != int64(offset) {
+		t.Errorf("unexpected offset %d", invoker.Offset)
+	}
+}
+
+func TestScanProxy(t *testing.T) {
+	handler := emptyObjectHex("sun.reflect.annotation.AnnotationInvocationHandler")
+	proxy := tcObject + tcProxyClassDesc + "00000001" + encodeStr("java.util.Map") + tcEndBlockData + tcNull
+	findings, err := scanHex(t, nil, streamMagic+streamVersion+objectArrayHex(proxy, handler))
+	if err == nil {
+		t.Error("expected error")
+	}
+	if !reflect.DeepEqual(findingRules(findings), []string{"annotation-invocation-handler"}) {
+		t.Errorf("unexpected findings %v", findings)
+	}
+}
+
+func TestScanClean(t *testing.T) {
+	s, err := NewScanner(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	findings, err := s.Scan(objs["hashMapStr"])
+	if err != nil || len(findings) != 0 {
+		t.Errorf("unexpected findings %v, error %+v", findings, err)
+	}
+}
+
+func TestScanCustomRules(t *testing.T) {
+	rules := append(DefaultGadgetRules(), GadgetRule{
+		Name:     "custom",
+		Severity: SeverityMedium,
+		Classes:  []string{"java.base/java.util.*", "java.lang.Integer"},
+	})
+	findings, err := scanHex(t, rules, hex.EncodeToString(objs["hashMapStr"]))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(findings) != 1 || findings[0].Class != "java.lang.Integer" || findings[0].Severity.String() != "medium" {
+		t.Errorf("unexpected findings %v", findings)
+	}
+
+	for _, rule := range []GadgetRule{{Name: "empty"}, {Name: "reject", Classes: []string{"!foo.Bar"}}} {
+		if _, err := NewScanner([]GadgetRule{rule}); err == nil {
+			t.Errorf("%s: expected error", rule.Name)
+		}
+	}
+}
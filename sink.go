@@ -0,0 +1,69 @@
+package jserial
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Sink receives the payload of a large byte array or block data as a stream. The path locates the value like the
+// Path of a ParseError, e.g. `com.acme.Document.content`, and r reads exactly the payload. Bytes left unread by the
+// sink are skipped and an error aborts parsing.
+type Sink func(path string, r io.Reader) error
+
+// SinkRef stands in the decoded content for a payload passed to a Sink.
+type SinkRef struct {
+	// Path is the path passed to the sink.
+	Path string
+	// Offset is the position of the payload in the stream.
+	Offset int64
+	// Size is the length of the payload in bytes.
+	Size int64
+}
+
+// WithSink makes the parser pass byte arrays and block data of at least minSize bytes to sink instead of buffering
+// them, so their size is not bounded by SetMaxBlockDataSize or SetMaxArrayLength nor accounted for by
+// SetMaxAllocBytes. They are replaced by a *SinkRef in the decoded content. The block data and byte arrays written
+// directly by the writeObject method of a class with a post processor, which reads them, and block data read by the
+// ObjectInputStream style methods, e.g. ReadInt, are never passed to the sink. Contents nested in those annotations,
+// e.g. the byte array field of an object in a list, are.
+func WithSink(minSize int, sink Sink) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.sink = sink
+		sop.sinkMinSize = int64(minSize)
+	}
+}
+
+// sinks reports whether a payload of size bytes is passed to the sink.
+func (sop *SerializedObjectParser) sinks(size int64) bool {
+	if sop.sink == nil || sop.indexing || sop.readingBlock || sop.depth == sop.procDepth {
+		return false
+	}
+
+	return size >= sop.sinkMinSize
+}
+
+// toSink passes the next size bytes to the sink and returns the reference replacing them.
+func (sop *SerializedObjectParser) toSink(size int64) (interface{}, error) {
+	if err := sop.checkInput(size); err != nil {
+		return nil, err
+	}
+
+	ref := &SinkRef{Path: sop.pathString(), Offset: sop.rd.pos, Size: size}
+	payload := &io.LimitedReader{R: sop.rd, N: size}
+
+	if err := sop.sink(ref.Path, payload); err != nil {
+		return nil, errors.Wrap(err, "error in sink")
+	}
+
+	if _, err := io.Copy(ioutil.Discard, payload); err != nil {
+		return nil, errors.Wrap(err, "error skipping the rest of the payload")
+	}
+
+	if payload.N > 0 {
+		return nil, errors.Wrap(io.ErrUnexpectedEOF, "error reading payload")
+	}
+
+	return ref, nil
+}
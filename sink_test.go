@@ -0,0 +1,231 @@
+package jserial
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func byteArrayFieldHex(data []byte) string {
+	return streamMagic + streamVersion + byteArrayObjectHex(data)
+}
+
+func byteArrayObjectHex(data []byte) string {
+	byteArrayClass := tcClassDesc + encodeStr("[B") + serialVer + scSerializable + "0000" + tcEndBlockData + tcNull
+
+	return tcObject + tcClassDesc + someClassEnc + serialVer + scSerializable + "0001" + "5b" +
+		encodeStr("data") + tcString + encodeStr("[B") + tcEndBlockData + tcNull +
+		tcArray + byteArrayClass + fmt.Sprintf("%08x", len(data)) + hex.EncodeToString(data)
+}
+
+func TestSinkByteArray(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+
+	var paths []string
+
+	hash := sha256.New()
+	sop := newTestParser(t, byteArrayFieldHex(data), WithSink(100, func(path string, r io.Reader) error {
+		paths = append(paths, path)
+		_, err := io.Copy(hash, r)
+		return err
+	}))
+
+	content, err := sop.ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"SomeClass.data"}) {
+		t.Errorf("unexpected paths %v", paths)
+	}
+	if sum := sha256.Sum256(data); !bytes.Equal(hash.Sum(nil), sum[:]) {
+		t.Error("unexpected payload")
+	}
+
+	ref, isRef := content[0].(map[string]interface{})["data"].(*SinkRef)
+	if !isRef {
+		t.Fatalf("unexpected content %v", content)
+	}
+	// the payload follows the fields of the stream without data
+	offset := int64(len(byteArrayFieldHex(nil)) / 2)
+	if expected := (SinkRef{Path: "SomeClass.data", Offset: offset, Size: int64(len(data))}); *ref != expected {
+		t.Errorf("unexpected reference %+v", ref)
+	}
+
+	// small arrays are decoded as usual
+	content, err = newTestParser(t, byteArrayFieldHex(data[:99]), WithSink(100, func(string, io.Reader) error {
+		t.Error("unexpected call to the sink")
+		return nil
+	})).ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !bytes.Equal(content[0].(map[string]interface{})["data"].([]byte), data[:99]) {
+		t.Errorf("unexpected content %v", content)
+	}
+
+	// arrays passed to the sink are not bounded by the array length limit
+	_, err = newTestParser(t, byteArrayFieldHex(data), SetMaxArrayLength(10), WithSink(100, func(string, io.Reader) error {
+		return nil
+	})).ParseSerializedObject()
+	if err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func TestSinkBlockDataLong(t *testing.T) {
+	data := bytes.Repeat([]byte{0xab}, 4*bufferSize)
+	b := decodeHex(t, streamMagic+streamVersion+tcBlockDataLong+fmt.Sprintf("%08x", len(data))+
+		hex.EncodeToString(data)+tcString+encodeStr("foo"))
+
+	// the payload is larger than the reader's buffer and the block data limit
+	_, err := NewSerializedObjectParser(bytes.NewReader(b), SetMaxBlockDataSize(bufferSize)).ParseSerializedObject()
+	if !errors.Is(err, ErrMaxBlockDataSize) {
+		t.Fatalf("unexpected error without a sink: %v", err)
+	}
+
+	// unread bytes are skipped
+	var head []byte
+
+	sink := func(path string, r io.Reader) error {
+		head = make([]byte, 10)
+		_, err := io.ReadFull(r, head)
+		return err
+	}
+
+	content, err := NewSerializedObjectParser(bytes.NewReader(b), SetMaxBlockDataSize(bufferSize), WithSink(0, sink)).
+		ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !bytes.Equal(head, data[:10]) {
+		t.Errorf("unexpected payload %x", head)
+	}
+	if !reflect.DeepEqual(content, []interface{}{&SinkRef{Offset: 9, Size: int64(len(data))}, "foo"}) {
+		t.Errorf("unexpected content %v", content)
+	}
+}
+
+func TestSinkErrors(t *testing.T) {
+	hexStr := streamMagic + streamVersion + tcBlockData + "03aabbcc"
+	errSink := errors.New("disk full")
+
+	_, err := newTestParser(t, hexStr, WithSink(0, func(string, io.Reader) error {
+		return errSink
+	})).ParseSerializedObject()
+	if !errors.Is(err, errSink) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the stream ends inside the payload
+	_, err = newTestParser(t, hexStr[:len(hexStr)-2], WithSink(0, func(_ string, r io.Reader) error {
+		_, err := io.Copy(ioutil.Discard, r)
+		return err
+	})).ParseSerializedObject()
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSinkObjectInput(t *testing.T) {
+	sop := newTestParser(t, streamMagic+streamVersion+tcBlockData+"0400000005", WithSink(0, func(string, io.Reader) error {
+		t.Error("unexpected call to the sink")
+		return nil
+	}))
+
+	if i, err := sop.ReadInt(); err != nil || i != 5 {
+		t.Errorf("unexpected int %d, error: %v", i, err)
+	}
+}
+
+func TestSinkAnnotations(t *testing.T) {
+	b := objs["hashMapStr"]
+	sink := func(path string, r io.Reader) error {
+		t.Errorf("unexpected call to the sink for %s", path)
+		return nil
+	}
+
+	// the block data holding the capacity and size of the map is read by its post processor
+	content, err := NewSerializedObjectParser(bytes.NewReader(b), WithSink(8, sink)).ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if expected, _ := ParseSerializedObject(b); !reflect.DeepEqual(content, expected) {
+		t.Errorf("unexpected content %v", content)
+	}
+
+	// neither are byte arrays written by writeObject
+	array := tcArray + tcClassDesc + encodeStr("[B") + serialVer + scSerializable + "0000" + tcEndBlockData + tcNull +
+		"00000003" + "aabbcc"
+	hexStr := streamPrefix + tcClassDesc + someClassEnc + serialVer + "03" + "0000" + tcEndBlockData + tcNull + array +
+		tcEndBlockData
+	registry := NewRegistry()
+	if err = registry.Register("SomeClass", func(r *BlockDataReader) (map[string]interface{}, error) {
+		return r.DefaultFields(), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if content, err = newTestParser(t, hexStr, WithSink(0, sink), WithPostProcs(registry)).
+		ParseSerializedObjectMinimal(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	anns := content[0].(map[string]interface{})["@"]
+	if !reflect.DeepEqual(anns, []interface{}{[]byte{0xaa, 0xbb, 0xcc}}) {
+		t.Errorf("unexpected annotations %v", anns)
+	}
+
+	// without a post processor they are passed to the sink
+	var paths []string
+
+	content, err = newTestParser(t, hexStr, WithSink(0, func(path string, r io.Reader) error {
+		paths = append(paths, path)
+		return nil
+	})).ParseSerializedObjectMinimal()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("unexpected paths %v", paths)
+	}
+}
+
+func TestSinkNestedInAnnotations(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	arrayListClass := tcClassDesc + encodeStr("java.util.ArrayList") + "7881d21d99c7619d" + "03" + "0001" + "49" +
+		encodeStr("size") + tcEndBlockData + tcNull
+	hexStr := streamPrefix + arrayListClass + "00000001" + tcBlockData + "04" + "00000001" +
+		byteArrayObjectHex(data) + tcEndBlockData
+
+	var paths []string
+
+	hash := sha256.New()
+	sop := newTestParser(t, hexStr, WithSink(100, func(path string, r io.Reader) error {
+		paths = append(paths, path)
+		_, err := io.Copy(hash, r)
+		return err
+	}))
+
+	// the list is read by its post processor, the byte array field of its element is passed to the sink
+	content, err := sop.ParseSerializedObjectMinimal()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+	if sum := sha256.Sum256(data); !bytes.Equal(hash.Sum(nil), sum[:]) {
+		t.Error("unexpected payload")
+	}
+	list, isList := content[0].([]interface{})
+	if !isList || len(list) != 1 {
+		t.Fatalf("unexpected content %v", content)
+	}
+	if ref, isRef := list[0].(map[string]interface{})["data"].(*SinkRef); !isRef || ref.Size != int64(len(data)) {
+		t.Errorf("unexpected element %v", list[0])
+	}
+}
@@ -0,0 +1,175 @@
+package jserial
+
+import (
+	"bytes"
+	"io"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// sliceSource is a byteSource reading directly from an in-memory slice.
+type sliceSource struct {
+	buf []byte
+	off int
+}
+
+// NewSerializedObjectParserBytes reads serialized java objects from buf without copying: block data and byte arrays
+// are returned as subslices of buf. The caller must not modify buf while the parsed values are in use. The length of
+// strings, block data and arrays is only bounded by the size of buf unless limited with SetMaxStringLength,
+// SetMaxBlockDataSize or SetMaxArrayLength.
+func NewSerializedObjectParserBytes(buf []byte, options ...Option) *SerializedObjectParser {
+	sop := NewSerializedObjectParser(bytes.NewReader(buf), append([]Option{inMemoryLimits}, options...)...)
+	sop.rd.br = &sliceSource{buf: buf}
+	sop.zeroCopy = true
+
+	return sop
+}
+
+// SetUnsafeStrings makes a parser created by NewSerializedObjectParserBytes return strings sharing memory with its
+// input instead of copies, like unsafe.String. Modifying the input afterwards changes the strings, which breaks the
+// guarantees of the language, so the input must never be modified once parsed. Other parsers ignore this option.
+func SetUnsafeStrings(unsafeStrings bool) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.unsafeStrings = unsafeStrings
+	}
+}
+
+// readBytes reads the next n bytes, which share memory with the input of a parser created by
+// NewSerializedObjectParserBytes.
+func (sop *SerializedObjectParser) readBytes(n int) (b []byte, err error) {
+	if sop.zeroCopy {
+		return sop.rd.take(n)
+	}
+
+	if sop.indexing {
+		return nil, sop.skip(int64(n))
+	}
+
+	if _, isSlice := sop.rd.br.(*sliceSource); isSlice {
+		// the copy is never larger than the input, whatever size the stream claims
+		var data []byte
+
+		data, err = sop.rd.take(n)
+		b = make([]byte, len(data))
+		copy(b, data)
+
+		return
+	}
+
+	b = make([]byte, n)
+	_, err = io.ReadFull(sop.rd, b)
+
+	return
+}
+
+// bytesToString converts b to a string, without copying if unsafe strings are enabled.
+func (sop *SerializedObjectParser) bytesToString(b []byte) string {
+	if sop.unsafeStrings && sop.zeroCopy {
+		return unsafe.String(unsafe.SliceData(b), len(b))
+	}
+
+	return string(b)
+}
+
+// take consumes the next n bytes of a sliceSource and returns them without copying. Like io.ReadFull it returns
+// io.ErrUnexpectedEOF along with the remaining bytes if there are fewer than n.
+func (r *reader) take(n int) (b []byte, err error) {
+	src, isSlice := r.br.(*sliceSource)
+	if !isSlice {
+		return nil, errors.New("input is not a slice")
+	}
+
+	if b, err = src.take(n); err != nil {
+		r.eof = true
+	}
+
+	r.record(b)
+
+	return
+}
+
+func (s *sliceSource) take(n int) (b []byte, err error) {
+	remaining := len(s.buf) - s.off
+
+	switch {
+	case remaining == 0 && n > 0:
+		return nil, io.EOF
+	case remaining < n:
+		n, err = remaining, io.ErrUnexpectedEOF
+	}
+
+	b = s.buf[s.off : s.off+n : s.off+n]
+	s.off += n
+
+	return
+}
+
+func (s *sliceSource) Read(p []byte) (n int, err error) {
+	if s.off >= len(s.buf) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+
+		return 0, io.EOF
+	}
+
+	n = copy(p, s.buf[s.off:])
+	s.off += n
+
+	return
+}
+
+func (s *sliceSource) ReadByte() (byte, error) {
+	if s.off >= len(s.buf) {
+		return 0, io.EOF
+	}
+
+	s.off++
+
+	return s.buf[s.off-1], nil
+}
+
+func (s *sliceSource) UnreadByte() error {
+	if s.off == 0 {
+		return errors.New("no byte to unread")
+	}
+
+	s.off--
+
+	return nil
+}
+
+// Discard skips the next n bytes like bufio.Reader.Discard.
+func (s *sliceSource) Discard(n int) (discarded int, err error) {
+	if remaining := len(s.buf) - s.off; remaining < n {
+		n, err = remaining, io.EOF
+	}
+
+	s.off += n
+
+	return n, err
+}
+
+func (s *sliceSource) Peek(n int) ([]byte, error) {
+	if remaining := len(s.buf) - s.off; remaining < n {
+		return s.buf[s.off:], io.EOF
+	}
+
+	return s.buf[s.off : s.off+n], nil
+}
+
+// Buffered returns the number of bytes left in the slice, which can all be peeked.
+func (s *sliceSource) Buffered() int {
+	return len(s.buf) - s.off
+}
+
+// Reset moves to the current position of r, which must be a seeker over the same slice, e.g. after seeking in lenient
+// mode.
+func (s *sliceSource) Reset(r io.Reader) {
+	if seeker, isSeeker := r.(io.Seeker); isSeeker {
+		if off, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			s.off = int(off)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package jserial
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	for name, buf := range objs {
+		expected, err := ParseSerializedObject(buf)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %+v", name, err)
+		}
+
+		for _, unsafeStrings := range []bool{false, true} {
+			content, err := NewSerializedObjectParserBytes(buf, SetUnsafeStrings(unsafeStrings)).ParseSerializedObject()
+			if err != nil {
+				t.Errorf("%s: unexpected error: %+v", name, err)
+			} else if !reflect.DeepEqual(content, expected) {
+				t.Errorf("%s: unexpected content %v", name, content)
+			}
+		}
+	}
+}
+
+func TestParseBytesZeroCopy(t *testing.T) {
+	header := streamMagic + streamVersion
+	buf := decodeHex(t, header+tcBlockData+"03616263"+primitiveArrayHex("B", 2, "0102")[len(header):]+
+		tcString+encodeStr("foo"))
+
+	content, err := NewSerializedObjectParserBytes(buf, SetUnsafeStrings(true)).ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(content) != 3 {
+		t.Fatalf("unexpected content %v", content)
+	}
+
+	block, arr := content[0].([]byte), content[1].([]byte)
+	if &block[0] != &buf[6] || &arr[0] != &buf[len(buf)-8] {
+		t.Error("block data and byte arrays must share memory with the input")
+	}
+
+	// strings share memory with the input as well
+	copy(buf[len(buf)-3:], "bar")
+	if content[2] != "bar" {
+		t.Errorf("unexpected string %v", content[2])
+	}
+}
+
+func TestParseBytesTruncated(t *testing.T) {
+	buf := decodeHex(t, primitiveArrayHex("B", 4, "0102"))
+
+	content, err := NewSerializedObjectParserBytes(buf, SetPartialResults(true)).ParseSerializedObject()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !reflect.DeepEqual(content, []interface{}{&PartialArray{Elements: []byte{1, 2}, Length: 4}}) {
+		t.Errorf("unexpected content %v", content)
+	}
+
+	if _, err = NewSerializedObjectParserBytes(buf[:len(buf)-3]).ParseSerializedObject(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func BenchmarkParseSerializedObjectBytes(b *testing.B) {
+	names := make([]string, 0, len(objs))
+	for name := range objs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buf := objs[name]
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(buf)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := NewSerializedObjectParserBytes(buf, SetUnsafeStrings(true)).ParseSerializedObject(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
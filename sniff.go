@@ -0,0 +1,173 @@
+package jserial
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Encoding is a wrapper around a serialized stream detected by Sniff.
+type Encoding string
+
+// Encodings detected by Sniff.
+const (
+	// EncodingBase64 is standard or URL safe base64, padded or not, which may be broken into lines.
+	EncodingBase64 Encoding = "base64"
+	// EncodingHex is hexadecimal in upper or lower case, which may contain whitespace.
+	EncodingHex Encoding = "hex"
+	// EncodingGzip is gzip compression.
+	EncodingGzip Encoding = "gzip"
+)
+
+// ErrNotSerialized is returned by Sniff and Open if the input is not a serialized stream in any known encoding.
+var ErrNotSerialized = errors.New("not a serialized stream")
+
+const (
+	// sniffLength is the number of bytes inspected to detect an encoding, enough to decode the first bytes of most
+	// gzip streams.
+	sniffLength = 512
+	// maxEncodings is the maximum number of nested encodings unwrapped.
+	maxEncodings = 4
+)
+
+// IsSerializedStream reports whether prefix is the start of a serialized stream, either raw or wrapped in any of the
+// encodings detected by Sniff. The prefix must be long enough to decode the complete stream header, e.g. 6 bytes of
+// base64 or 8 bytes of hex. For gzip a few hundred bytes are sufficient.
+func IsSerializedStream(prefix []byte) bool {
+	_, isStream := sniffPrefix(prefix, maxEncodings)
+
+	return isStream
+}
+
+// Sniff detects the encodings wrapping the serialized stream read from r, e.g. base64 (`rO0AB…`), hex (`aced0005…`)
+// or base64 of gzip (`H4sI…`). It returns a reader of the raw stream and the encodings, from the outermost to the
+// innermost, or ErrNotSerialized.
+func Sniff(r io.Reader) (raw io.Reader, encodings []Encoding, err error) {
+	for {
+		br := bufio.NewReaderSize(r, sniffLength)
+		prefix, _ := br.Peek(sniffLength)
+
+		encoding, isStream := sniffPrefix(prefix, maxEncodings-len(encodings))
+		if !isStream {
+			return nil, nil, errors.WithStack(ErrNotSerialized)
+		}
+
+		if encoding == "" {
+			return br, encodings, nil
+		}
+
+		if r, err = decoder(encoding, br); err != nil {
+			return nil, nil, errors.Wrapf(err, "error decoding %s", encoding)
+		}
+
+		encodings = append(encodings, encoding)
+	}
+}
+
+// Open returns a parser of the serialized stream read from r, which may be wrapped in any of the encodings detected
+// by Sniff.
+func Open(r io.Reader, options ...Option) (*SerializedObjectParser, error) {
+	raw, _, err := Sniff(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSerializedObjectParser(raw, options...), nil
+}
+
+// sniffPrefix returns the outermost encoding of the serialized stream starting with prefix, which is empty for a raw
+// stream, and whether prefix is a serialized stream wrapped in at most depth encodings.
+func sniffPrefix(prefix []byte, depth int) (Encoding, bool) {
+	if bytes.HasPrefix(prefix, streamHeader) {
+		return "", true
+	}
+
+	if depth == 0 {
+		return "", false
+	}
+
+	for _, encoding := range []Encoding{EncodingGzip, EncodingBase64, EncodingHex} {
+		rd, err := decoder(encoding, bytes.NewReader(prefix))
+		if err != nil {
+			continue
+		}
+
+		// the decoded prefix ends with an error where prefix was cut off
+		decoded, _ := ioutil.ReadAll(io.LimitReader(rd, sniffLength))
+
+		if _, isStream := sniffPrefix(decoded, depth-1); isStream {
+			return encoding, true
+		}
+	}
+
+	return "", false
+}
+
+// decoder returns a reader decoding encoding from r.
+func decoder(encoding Encoding, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewReader(r)
+	case EncodingBase64:
+		return base64.NewDecoder(base64.RawStdEncoding, &filterReader{rd: r, filter: base64Filter}), nil
+	case EncodingHex:
+		return hex.NewDecoder(&filterReader{rd: r, filter: hexFilter}), nil
+	default:
+		return nil, errors.Errorf("unknown encoding %s", encoding)
+	}
+}
+
+// filterReader drops or replaces the bytes read from rd.
+type filterReader struct {
+	rd     io.Reader
+	filter func(c byte) (byte, bool)
+}
+
+func (f *filterReader) Read(p []byte) (n int, err error) {
+	// nothing can be read into an empty p, reading from rd would never make progress
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for n == 0 && err == nil {
+		var read int
+
+		read, err = f.rd.Read(p)
+
+		for _, c := range p[:read] {
+			if c, keep := f.filter(c); keep {
+				p[n] = c
+				n++
+			}
+		}
+	}
+
+	return
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// base64Filter drops whitespace and padding and maps the URL safe alphabet to the standard one.
+func base64Filter(c byte) (byte, bool) {
+	switch {
+	case c == '-':
+		return '+', true
+	case c == '_':
+		return '/', true
+	default:
+		return c, !isSpace(c) && c != '='
+	}
+}
+
+// hexFilter drops whitespace.
+func hexFilter(c byte) (byte, bool) {
+	return c, !isSpace(c)
+}
@@ -0,0 +1,130 @@
+package jserial
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestSniff(t *testing.T) {
+	raw := objs["longStr"]
+	option := SetMaxDataBlockSize(len(raw))
+	expected, err := ParseSerializedObject(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	b64 := func(b []byte) []byte { return []byte(base64.StdEncoding.EncodeToString(b)) }
+	wrapped := func(b []byte) []byte {
+		var sb strings.Builder
+		for s := string(b64(b)); len(s) > 0; {
+			n := 76
+			if n > len(s) {
+				n = len(s)
+			}
+			sb.WriteString(s[:n] + "\r\n")
+			s = s[n:]
+		}
+		return []byte(sb.String())
+	}
+	hexUpper := func(b []byte) []byte { return []byte(strings.ToUpper(hex.EncodeToString(b))) }
+
+	tests := []struct {
+		name      string
+		input     []byte
+		encodings []Encoding
+	}{
+		{"raw", raw, nil},
+		{"base64", b64(raw), []Encoding{EncodingBase64}},
+		{"base64 url", []byte(base64.RawURLEncoding.EncodeToString(raw)), []Encoding{EncodingBase64}},
+		{"base64 lines", wrapped(raw), []Encoding{EncodingBase64}},
+		{"hex", []byte(hex.EncodeToString(raw)), []Encoding{EncodingHex}},
+		{"hex upper", hexUpper(raw), []Encoding{EncodingHex}},
+		{"gzip", gzipBytes(t, raw), []Encoding{EncodingGzip}},
+		{"base64 gzip", b64(gzipBytes(t, raw)), []Encoding{EncodingBase64, EncodingGzip}},
+		{"hex base64 gzip", []byte(hex.EncodeToString(b64(gzipBytes(t, raw)))),
+			[]Encoding{EncodingHex, EncodingBase64, EncodingGzip}},
+	}
+
+	for _, test := range tests {
+		if !IsSerializedStream(test.input) {
+			t.Errorf("%s: not detected as serialized stream", test.name)
+		}
+
+		rd, encodings, err := Sniff(bytes.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %+v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(encodings, test.encodings) {
+			t.Errorf("%s: unexpected encodings %v", test.name, encodings)
+		}
+
+		content, err := NewSerializedObjectParser(rd, option).ParseSerializedObject()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %+v", test.name, err)
+		} else if !reflect.DeepEqual(content, expected) {
+			t.Errorf("%s: unexpected content", test.name)
+		}
+	}
+}
+
+func TestSniffPrefix(t *testing.T) {
+	for _, prefix := range []string{"\xac\xed\x00\x05", "rO0ABXVy", "aced0005", "ACED 0005 7372"} {
+		if !IsSerializedStream([]byte(prefix)) {
+			t.Errorf("prefix %q not detected", prefix)
+		}
+	}
+
+	for _, prefix := range []string{"", "\xac\xed", "hello world", "{\"rO0AB\": 1}", "H4sIAAAA"} {
+		if IsSerializedStream([]byte(prefix)) {
+			t.Errorf("prefix %q detected", prefix)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	sop, err := Open(strings.NewReader(base64.StdEncoding.EncodeToString(objs["canary"])))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if _, err = sop.ParseSerializedObject(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	if _, err = Open(strings.NewReader("not serialized")); !errors.Is(err, ErrNotSerialized) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFilterReaderEmptyRead(t *testing.T) {
+	r := &filterReader{rd: strings.NewReader("00 01"), filter: hexFilter}
+	if n, err := r.Read(nil); n != 0 || err != nil {
+		t.Errorf("unexpected read of %d bytes, error: %v", n, err)
+	}
+
+	p := make([]byte, 8)
+	if n, err := r.Read(p); err != nil || string(p[:n]) != "0001" {
+		t.Errorf("unexpected read %q, error: %v", p[:n], err)
+	}
+}
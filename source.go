@@ -0,0 +1,141 @@
+package jserial
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// byteSource is the input read by the parser, it is implemented by *bufio.Reader.
+type byteSource interface {
+	io.Reader
+	io.ByteScanner
+	Peek(n int) ([]byte, error)
+	Buffered() int
+	Reset(r io.Reader)
+}
+
+// exactSource reads only the bytes needed by the parser from the underlying reader. Bytes are only held back when
+// the parser peeks ahead and the underlying reader cannot unread them.
+type exactSource struct {
+	rd      io.Reader
+	pending []byte // bytes read from rd which have not been consumed yet
+	last    int    // last byte consumed for UnreadByte or -1
+	one     [1]byte
+}
+
+// SetExactReads makes the parser read exactly the bytes of the serialized data from the underlying reader instead of
+// reading ahead into a buffer, so the reader can be used for other data once the parser is done, e.g. after Next has
+// returned an object. Reads are unbuffered unless the reader is a *bufio.Reader, which is then read from directly, so
+// wrapping a reader into a *bufio.Reader is recommended. Any bytes the parser had to read ahead are returned by
+// Buffered.
+func SetExactReads(exact bool) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.exactReads = exact
+	}
+}
+
+// Buffered returns a reader of the bytes which have been read from the underlying reader but not consumed by the
+// parser. Like with json.Decoder.Buffered, the reader is valid until the next call to the parser.
+func (sop *SerializedObjectParser) Buffered() io.Reader {
+	b, _ := sop.rd.Peek(sop.rd.Buffered())
+
+	return bytes.NewReader(b)
+}
+
+// newExactSource returns a byteSource consuming exactly the bytes read from rd.
+func newExactSource(rd io.Reader) byteSource {
+	if src, isSource := rd.(byteSource); isSource {
+		return src
+	}
+
+	return &exactSource{rd: rd, last: -1}
+}
+
+func (s *exactSource) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if len(s.pending) > 0 {
+		n = copy(p, s.pending)
+		s.pending = s.pending[n:]
+	} else if n, err = s.rd.Read(p); n == 0 {
+		return
+	}
+
+	s.last = int(p[n-1])
+
+	return
+}
+
+func (s *exactSource) ReadByte() (b byte, err error) {
+	if len(s.pending) > 0 {
+		b, s.pending = s.pending[0], s.pending[1:]
+	} else if br, isByteReader := s.rd.(io.ByteReader); isByteReader {
+		if b, err = br.ReadByte(); err != nil {
+			return
+		}
+	} else {
+		if _, err = io.ReadFull(s.rd, s.one[:]); err != nil {
+			return
+		}
+
+		b = s.one[0]
+	}
+
+	s.last = int(b)
+
+	return
+}
+
+func (s *exactSource) UnreadByte() error {
+	if s.last < 0 {
+		return errors.New("no byte to unread")
+	}
+
+	s.pending = append([]byte{byte(s.last)}, s.pending...)
+	s.last = -1
+
+	return nil
+}
+
+// Peek returns the next n bytes without consuming them. A single byte is peeked by reading and unreading it from
+// the underlying reader if it is an io.ByteScanner, otherwise the bytes are held back until they are consumed.
+func (s *exactSource) Peek(n int) ([]byte, error) {
+	if scanner, isScanner := s.rd.(io.ByteScanner); isScanner && n == 1 && len(s.pending) == 0 {
+		b, err := scanner.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		s.one[0] = b
+
+		return s.one[:], scanner.UnreadByte()
+	}
+
+	if missing := n - len(s.pending); missing > 0 {
+		buf := make([]byte, missing)
+		read, err := io.ReadFull(s.rd, buf)
+		s.pending = append(s.pending, buf[:read]...)
+
+		if err != nil {
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+
+			return s.pending, err
+		}
+	}
+
+	return s.pending[:n], nil
+}
+
+func (s *exactSource) Buffered() int {
+	return len(s.pending)
+}
+
+func (s *exactSource) Reset(rd io.Reader) {
+	s.rd, s.pending, s.last = rd, nil, -1
+}
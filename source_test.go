@@ -0,0 +1,85 @@
+package jserial
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+const trailer = "TRAILER"
+
+func TestExactReads(t *testing.T) {
+	b := append(decodeHex(t, streamMagic+streamVersion+tcString+encodeStr("foo")), trailer...)
+
+	for name, newReader := range map[string]func() (io.Reader, io.Reader){
+		"reader": func() (io.Reader, io.Reader) {
+			rd := struct{ io.Reader }{bytes.NewReader(b)}
+			return rd, rd
+		},
+		"byte scanner": func() (io.Reader, io.Reader) {
+			rd := bytes.NewReader(b)
+			return rd, rd
+		},
+		"bufio": func() (io.Reader, io.Reader) {
+			rd := bufio.NewReader(bytes.NewReader(b))
+			return rd, rd
+		},
+	} {
+		rd, rest := newReader()
+		sop := NewSerializedObjectParser(rd, SetExactReads(true))
+		if obj, err := sop.Next(); err != nil || obj != "foo" {
+			t.Fatalf("%s: unexpected object %v, error %+v", name, obj, err)
+		}
+		if remainder, err := ioutil.ReadAll(rest); err != nil || string(remainder) != trailer {
+			t.Errorf("%s: unexpected remainder %q, error %+v", name, remainder, err)
+		}
+	}
+}
+
+func TestExactReadsPeek(t *testing.T) {
+	b := append(decodeHex(t, streamMagic+streamVersion+tcString+encodeStr("foo")), trailer...)
+	sop := NewSerializedObjectParser(struct{ io.Reader }{bytes.NewReader(b)}, SetExactReads(true))
+	if _, err := sop.Next(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	// peeking ahead holds back bytes from the underlying reader
+	if _, err := sop.rd.Peek(3); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if remainder, _ := ioutil.ReadAll(sop.Buffered()); string(remainder) != trailer[:3] {
+		t.Errorf("unexpected remainder %q", remainder)
+	}
+}
+
+func TestBufferedRemainder(t *testing.T) {
+	b := append(decodeHex(t, streamMagic+streamVersion+tcString+encodeStr("foo")), trailer...)
+	rd := bytes.NewReader(b)
+	sop := NewSerializedObjectParser(rd)
+	if _, err := sop.Next(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if rd.Len() != 0 {
+		t.Errorf("expected the reader to be read ahead")
+	}
+	remainder, _ := ioutil.ReadAll(sop.Buffered())
+	if string(remainder) != trailer {
+		t.Errorf("unexpected remainder %q", remainder)
+	}
+}
+
+func TestExactReadsParse(t *testing.T) {
+	b := objs["hashMapStr"]
+	expected, err := ParseSerializedObjectMinimal(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	sop := NewSerializedObjectParser(struct{ io.Reader }{bytes.NewReader(b)}, SetExactReads(true),
+		SetMaxDataBlockSize(len(b)))
+	content, err := sop.ParseSerializedObjectMinimal()
+	if err != nil || !reflect.DeepEqual(content, expected) {
+		t.Errorf("unexpected content %v, error %+v", content, err)
+	}
+}
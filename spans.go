@@ -0,0 +1,222 @@
+package jserial
+
+import "reflect"
+
+// Span locates a value read from the stream.
+type Span struct {
+	// Offset is the position of the value's type code in the stream.
+	Offset int64
+	// Length is the number of bytes of the value including its type code and any nested values, or -1 if the value
+	// could not be read completely.
+	Length int64
+	// TypeCode is the type code of the value, e.g. 0x73 for TC_OBJECT.
+	TypeCode byte
+	// Handle is the handle assigned to the value, the handle a reference refers to, or -1.
+	Handle int
+	// Class is the name of the class of an object, array, enum or class descriptor.
+	Class string
+	// Path leads from the outermost object to the value, it is empty for top level values.
+	Path string
+	// Value is the value in the full representation returned by ParseSerializedObject.
+	Value interface{}
+}
+
+// Type returns the name of the span's type code, e.g. "Object".
+func (s *Span) Type() string {
+	if tc := s.TypeCode - typeMask; tc <= typeNameMax {
+		return typeNames[tc]
+	}
+
+	return "unknown"
+}
+
+// SetRecordSpans makes the parser record the Span of every value (objects, class descriptors, strings, arrays, block
+// data, references, ...) it reads, which are returned by Spans.
+func SetRecordSpans(record bool) Option {
+	return func(sop *SerializedObjectParser) {
+		sop.recordSpans = record
+	}
+}
+
+// Spans returns the spans of the values read so far, ordered by offset.
+func (sop *SerializedObjectParser) Spans() []Span {
+	return sop.spans
+}
+
+// spanKey locates a value of the full representation: a node, i.e. an object, array or annotations, or the value a
+// node holds under a field name or index.
+type spanKey struct {
+	node  uintptr
+	field string
+	index int
+}
+
+// SpanOf returns the span of node, an object, array or *Undecodable of the full representation returned by
+// ParseSerializedObject, or the annotations of an object (its "@" value).
+func (sop *SerializedObjectParser) SpanOf(node interface{}) (Span, bool) {
+	return sop.lookupSpan(node, "", -1)
+}
+
+// FieldSpan returns the span of the value of the field name of obj, an object of the full representation, e.g. to
+// locate a string. Primitive fields have no span of their own and neither have the fields of a class whose values are
+// rebuilt by a post processor.
+func (sop *SerializedObjectParser) FieldSpan(obj map[string]interface{}, name string) (Span, bool) {
+	return sop.lookupSpan(obj, name, -1)
+}
+
+// ElemSpan returns the span of the i-th element of node, an array of objects or the annotations of an object of the
+// full representation.
+func (sop *SerializedObjectParser) ElemSpan(node interface{}, i int) (Span, bool) {
+	if i < 0 {
+		return Span{}, false
+	}
+
+	return sop.lookupSpan(node, "", i)
+}
+
+// lookupSpan returns the span recorded for the value node holds under field or index, or for node itself.
+func (sop *SerializedObjectParser) lookupSpan(node interface{}, field string, index int) (Span, bool) {
+	id := nodeID(node)
+	if id == 0 {
+		return Span{}, false
+	}
+
+	idx, exists := sop.spanIndex[spanKey{node: id, field: field, index: index}]
+	if !exists {
+		return Span{}, false
+	}
+
+	return sop.spans[idx], true
+}
+
+// nodeID returns the identity of a map, slice or pointer, or 0 for other values and empty slices, which may share
+// their address.
+func nodeID(node interface{}) uintptr {
+	switch v := reflect.ValueOf(node); v.Kind() {
+	case reflect.Map, reflect.Ptr:
+		return v.Pointer()
+	case reflect.Slice:
+		if v.Len() > 0 {
+			return v.Pointer()
+		}
+	}
+
+	return 0
+}
+
+// linkSpan records span idx as the span of the value node holds under field or index, or of node itself.
+func (sop *SerializedObjectParser) linkSpan(node interface{}, field string, index, idx int) {
+	id := nodeID(node)
+	if idx < 0 || id == 0 {
+		return
+	}
+
+	if sop.spanIndex == nil {
+		sop.spanIndex = map[spanKey]int{}
+	}
+
+	sop.spanIndex[spanKey{node: id, field: field, index: index}] = idx
+}
+
+// appendSpan appends the span of the value just read to spans, if spans are recorded.
+func (sop *SerializedObjectParser) appendSpan(spans []int) []int {
+	if !sop.recordSpans {
+		return spans
+	}
+
+	return append(spans, sop.lastSpan)
+}
+
+// linkElemSpans records the spans of the elements of node collected by appendSpan.
+func (sop *SerializedObjectParser) linkElemSpans(node interface{}, spans []int) {
+	for i, idx := range spans {
+		sop.linkSpan(node, "", i, idx)
+	}
+}
+
+// linkFieldSpan records the span of the value just read as the span of the field name of obj.
+func (sop *SerializedObjectParser) linkFieldSpan(obj map[string]interface{}, name string) {
+	if sop.recordSpans {
+		sop.linkSpan(obj, name, -1, sop.lastSpan)
+	}
+}
+
+// copyFieldSpans records the spans of the fields of from for the same fields of to.
+func (sop *SerializedObjectParser) copyFieldSpans(from, to map[string]interface{}) {
+	if sop.spanIndex == nil {
+		return
+	}
+
+	id := nodeID(from)
+
+	for name := range from {
+		if idx, exists := sop.spanIndex[spanKey{node: id, field: name, index: -1}]; exists {
+			sop.linkSpan(to, name, -1, idx)
+		}
+	}
+}
+
+// beginSpan starts the span of a value whose type code has just been read and returns its index, or -1 if spans are
+// not recorded.
+func (sop *SerializedObjectParser) beginSpan(tc byte) int {
+	if !sop.recordSpans {
+		return -1
+	}
+
+	sop.spans = append(sop.spans, Span{
+		Offset:   sop.contentOffset,
+		Length:   -1,
+		TypeCode: tc,
+		Handle:   -1,
+		Path:     sop.pathString(),
+	})
+
+	return len(sop.spans) - 1
+}
+
+// endSpan completes the span started by beginSpan once value has been read.
+func (sop *SerializedObjectParser) endSpan(idx int, value interface{}) {
+	const tcReference = 0x71
+
+	if idx < 0 {
+		return
+	}
+
+	span := &sop.spans[idx]
+	span.Length = sop.rd.pos - span.Offset
+	span.Value = value
+
+	sop.lastSpan = idx
+
+	// a reference is not the span of the value it refers to
+	if span.TypeCode != tcReference {
+		sop.linkSpan(value, "", -1, idx)
+	}
+
+	switch v := value.(type) {
+	case *clazz:
+		span.Class = v.name
+	case map[string]interface{}:
+		if cls, isClazz := v["class"].(*clazz); isClazz {
+			span.Class = cls.name
+		}
+	}
+}
+
+// spanClass records the class of the innermost value being read.
+func (sop *SerializedObjectParser) spanClass(name string) {
+	if sop.openSpan >= 0 {
+		sop.spans[sop.openSpan].Class = name
+	}
+}
+
+// spanHandle records the handle of the innermost value being read.
+func (sop *SerializedObjectParser) spanHandle(handle int) {
+	if sop.openSpan < 0 {
+		return
+	}
+
+	if span := &sop.spans[sop.openSpan]; span.Handle < 0 {
+		span.Handle = handle
+	}
+}
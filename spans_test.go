@@ -0,0 +1,103 @@
+package jserial
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	b := objs["hashMapStr"]
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetMaxDataBlockSize(len(b)), SetRecordSpans(true))
+	content, err := sop.ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	spans := sop.Spans()
+	expected := Span{Offset: 4, Length: 53, TypeCode: 0x75, Handle: 1,
+		Class: "[Ljava.lang.Object;", Value: content[0]}
+	if !reflect.DeepEqual(spans[0], expected) {
+		t.Errorf("unexpected top level span %+v", spans[0])
+	}
+
+	expected = Span{Offset: 44, Length: 8, TypeCode: 0x74, Handle: 2, Path: "[Ljava.lang.Object;[0]", Value: "Begin"}
+	// the class descriptor, its end of annotations and its null super class precede the first element
+	if !reflect.DeepEqual(spans[4], expected) {
+		t.Errorf("unexpected string span %+v", spans[4])
+	}
+
+	for i, span := range spans {
+		if span.Length <= 0 || span.Offset+span.Length > int64(len(b)) || b[span.Offset] != span.TypeCode {
+			t.Errorf("%d: invalid span %+v", i, span)
+		}
+		if i > 0 && span.Offset < spans[i-1].Offset {
+			t.Errorf("%d: span out of order %+v", i, span)
+		}
+		switch span.Type() {
+		case "ClassDesc", "Object", "String":
+			if span.Handle < 0 {
+				t.Errorf("%d: missing handle %+v", i, span)
+			}
+		case "Reference":
+			if !reflect.DeepEqual(span.Value, sop.handles[span.Handle]) {
+				t.Errorf("%d: reference to wrong handle %+v", i, span)
+			}
+		}
+	}
+}
+
+func TestSpanLookup(t *testing.T) {
+	b := decodeHex(t, streamPrefix+tcClassDesc+someClassEnc+serialVer+scSerializable+"0001"+
+		hex.EncodeToString([]byte("L"))+fooEnc+tcString+encodeStr("Ljava/lang/String;")+tcEndBlockData+tcNull+
+		tcString+encodeStr("bar"))
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetRecordSpans(true))
+	content, err := sop.ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	obj, _ := content[0].(map[string]interface{})
+	span, found := sop.FieldSpan(obj, "foo")
+	expected := Span{Offset: 57, Length: 6, TypeCode: 0x74, Handle: 3, Path: "SomeClass.foo", Value: "bar"}
+	if !found || !reflect.DeepEqual(span, expected) {
+		t.Errorf("unexpected field span %+v", span)
+	}
+
+	// the fields are also found in the map of their class
+	extends, _ := obj["extends"].(map[string]interface{})
+	if span, found = sop.FieldSpan(extends["SomeClass"].(map[string]interface{}), "foo"); !found || span.Offset != 57 {
+		t.Errorf("unexpected class field span %+v", span)
+	}
+
+	if span, found = sop.SpanOf(obj); !found || span.Offset != 4 || span.Length != int64(len(b))-4 {
+		t.Errorf("unexpected object span %+v", span)
+	}
+
+	if _, found = sop.FieldSpan(obj, "bar"); found {
+		t.Error("unexpected span of a missing field")
+	}
+}
+
+func TestSpanLookupElements(t *testing.T) {
+	b := objs["hashMapStr"]
+	sop := NewSerializedObjectParser(bytes.NewReader(b), SetRecordSpans(true))
+	content, err := sop.ParseSerializedObject()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if span, found := sop.ElemSpan(content[0], 0); !found || span.Offset != 44 || span.Value != "Begin" {
+		t.Errorf("unexpected element span %+v", span)
+	}
+
+	// the second element refers to the array itself
+	if span, found := sop.ElemSpan(content[0], 1); !found || span.Type() != "Reference" {
+		t.Errorf("unexpected element span %+v", span)
+	}
+
+	if span, found := sop.SpanOf(content[0]); !found || span.Offset != 4 {
+		t.Errorf("unexpected array span %+v", span)
+	}
+}